@@ -0,0 +1,25 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Acknowledge verified webhooks with a 200 straight away and process them in the background.
+// Shopify retries webhooks not answered within a few seconds, so slow work can be moved
+// off the request. The queue receives the shop, the topic, and a copy of the body,
+// which is safe to keep. It runs in its own goroutine, instead of the next handler,
+// so it should recover from its own panics.
+// Example: `WebhookVerify("abc123", nil, WithAsync(enqueue))`.
+func WithAsync(queue func(shop, topic string, body []byte)) Option {
+	return func(cfg *config) {
+		cfg.async = queue
+	}
+}
+
+// Hand the verified webhook to the queue and acknowledge it.
+// The body is the request's own copy, so is safe to keep once the request is done.
+func serveAsync(cfg *config, body []byte, w http.ResponseWriter, r *http.Request) {
+	shop, _ := ShopFromContext(r.Context())
+	topic, _ := TopicFromContext(r.Context())
+	go cfg.async(shop, topic, body)
+
+	w.WriteHeader(http.StatusOK)
+}
@@ -0,0 +1,87 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Test the webhook is acknowledged before the queued work is done.
+func TestWithAsync(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+
+	release := make(chan struct{})
+	done := make(chan struct{})
+	var gotShop, gotTopic string
+	var gotBody []byte
+	queue := func(shop, topic string, bb []byte) {
+		<-release
+		gotShop, gotTopic, gotBody = shop, topic, bb
+		close(done)
+	}
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithAsync(queue)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+
+	// Reuse pooled buffers before the queue reads the body.
+	for i := 0; i < 10; i++ {
+		serveWithOptions(`{"other":"payload"}`)
+	}
+
+	close(release)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("expected queue to run but did not")
+	}
+
+	if gotShop != "example.myshopify.com" {
+		t.Errorf("expected shop %q got %q", "example.myshopify.com", gotShop)
+	}
+
+	if gotTopic != "orders/create" {
+		t.Errorf("expected topic %q got %q", "orders/create", gotTopic)
+	}
+
+	if string(gotBody) != body {
+		t.Errorf("expected body %q got %q", body, gotBody)
+	}
+}
+
+// Test failed webhooks are not queued.
+func TestAsyncInvalid(t *testing.T) {
+	queued := make(chan struct{}, 1)
+	queue := func(shop, topic string, bb []byte) {
+		queued <- struct{}{}
+	}
+
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nil, WithAsync(queue)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	select {
+	case <-queued:
+		t.Errorf("expected queue to not run but did")
+	case <-time.After(10 * time.Millisecond):
+	}
+}
@@ -0,0 +1,28 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Stored webhook to verify, such as one pulled from a queue.
+type VerifyItem struct {
+	// Raw body as Shopify sent it.
+	Body []byte
+
+	// Headers as Shopify sent them, including the HMAC and the shop domain.
+	Header http.Header
+}
+
+// Verify a batch of stored webhooks with the secret key, without any HTTP handling.
+// Returns the reason each item failed, nil for valid ones, in the same order as the items.
+// Example: `errs := VerifyBatch("abc123", items)`.
+func VerifyBatch(key string, items []VerifyItem) []error {
+	errs := make([]error, len(items))
+	for i, item := range items {
+		shmac := strings.TrimSpace(item.Header.Get(DefaultHMACHeader))
+		errs[i] = verifyRequest(key, item.Header.Get(DefaultShopHeader), shmac, item.Body)
+	}
+
+	return errs
+}
@@ -0,0 +1,64 @@
+package http_shopify_webhook
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+)
+
+// Builds a stored webhook with the shop and HMAC headers.
+func newVerifyItem(body string, shop string, hmac string) VerifyItem {
+	h := make(http.Header)
+	if shop != "" {
+		h.Set("X-Shopify-Shop-Domain", shop)
+	}
+	if hmac != "" {
+		h.Set("X-Shopify-Hmac-Sha256", hmac)
+	}
+
+	return VerifyItem{Body: []byte(body), Header: h}
+}
+
+// Test each item gets its own result, in order.
+func TestVerifyBatch(t *testing.T) {
+	body := `{"key":"value"}`
+	shop := "example.myshopify.com"
+	tests := []struct {
+		item VerifyItem
+		err  error
+	}{
+		{item: newVerifyItem(body, shop, sign("secret", body)), err: nil},
+		{item: newVerifyItem(body, shop, sign("other", body)), err: ErrInvalidSignature},
+		{item: newVerifyItem(body, "", sign("secret", body)), err: ErrMissingShop},
+		{item: newVerifyItem(`{"other":"value"}`, shop, sign("secret", `{"other":"value"}`)), err: nil},
+		{item: newVerifyItem(body, shop, ""), err: ErrMissingHMAC},
+		{item: VerifyItem{Body: []byte(body)}, err: ErrMissingShop},
+	}
+
+	items := make([]VerifyItem, len(tests))
+	for i, tt := range tests {
+		items[i] = tt.item
+	}
+
+	errs := VerifyBatch("secret", items)
+	if len(errs) != len(items) {
+		t.Fatalf("expected %v results got %v", len(items), len(errs))
+	}
+
+	for i, tt := range tests {
+		if tt.err == nil && errs[i] != nil {
+			t.Errorf("item %v: expected no error got %v", i, errs[i])
+		}
+
+		if tt.err != nil && !errors.Is(errs[i], tt.err) {
+			t.Errorf("item %v: expected error %v got %v", i, tt.err, errs[i])
+		}
+	}
+}
+
+// Test an empty batch has no results.
+func TestVerifyBatchEmpty(t *testing.T) {
+	if errs := VerifyBatch("secret", nil); len(errs) != 0 {
+		t.Errorf("expected no results got %v", errs)
+	}
+}
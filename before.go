@@ -0,0 +1,23 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Call the function on every request before it is verified, such as to copy a
+// rewritten HMAC header back to its usual name. Changes to the request are seen by
+// the verifier. An error rejects the request, passed to the error handler as is,
+// and the default one writes a 400.
+// Example: `WebhookVerify("abc123", handler, WithBeforeVerify(restoreHeaders))`.
+func WithBeforeVerify(fn func(r *http.Request) error) Option {
+	return func(cfg *config) {
+		cfg.beforeVerify = fn
+	}
+}
+
+// Call the before verify hook, if set.
+func beforeVerify(cfg *config, r *http.Request) error {
+	if cfg.beforeVerify == nil {
+		return nil
+	}
+
+	return cfg.beforeVerify(r)
+}
@@ -0,0 +1,64 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Copy the HMAC from a vendor header back to the usual one.
+func restoreHMACHeader(r *http.Request) error {
+	if h := r.Header.Get("X-Vendor-Hmac"); h != "" {
+		r.Header.Set("X-Shopify-Hmac-Sha256", h)
+	}
+	return nil
+}
+
+// Test a header rewritten by the hook is verified.
+func TestWithBeforeVerify(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", "")
+	req.Header.Set("X-Vendor-Hmac", sign("secret", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithBeforeVerify(restoreHMACHeader)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+
+	// Without the hook, the HMAC is missing.
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", "")
+	req.Header.Set("X-Vendor-Hmac", sign("secret", body))
+	if err := serveForError(req); !errors.Is(err, ErrMissingHMAC) {
+		t.Errorf("expected error %v got %v", ErrMissingHMAC, err)
+	}
+}
+
+// Test an error from the hook rejects the request before the body is read.
+func TestWithBeforeVerifyError(t *testing.T) {
+	body := `{"key":"value"}`
+	tr := &trackingReader{Reader: bytes.NewBufferString(body)}
+	req := newWebhookRequest(tr, "example.myshopify.com", sign("secret", body))
+
+	errHook := errors.New("rejected by hook")
+	err := serveForError(req, WithBeforeVerify(func(r *http.Request) error {
+		return errHook
+	}))
+	if !errors.Is(err, errHook) {
+		t.Errorf("expected error %v got %v", errHook, err)
+	}
+
+	if tr.read {
+		t.Errorf("expected body to not be read but it was")
+	}
+}
@@ -0,0 +1,190 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Reject webhooks with an empty body.
+// By default, a missing body is treated the same as an empty one.
+// Example: `WebhookVerify("abc123", handler, WithRequireBody())`.
+func WithRequireBody() Option {
+	return func(cfg *config) {
+		cfg.requireBody = true
+	}
+}
+
+// Limit how long reading the webhook body can take, such as for stalled clients.
+// Reads over the limit, or cancelled through the request context, are rejected with a 408.
+// Example: `WebhookVerify("abc123", handler, WithReadTimeout(5 * time.Second))`.
+func WithReadTimeout(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.readTimeout = d
+	}
+}
+
+// Read the request body into the writer, or the body cached by `CacheBody`.
+// A nil or `http.NoBody` body is treated as an empty one.
+// Returns the number of bytes read.
+func readBody(cfg *config, dst io.Writer, w http.ResponseWriter, r *http.Request) (int64, error) {
+	if bb, ok := cachedBody(r.Context()); ok {
+		// Read upstream, the original body may be gone.
+		r.Body = io.NopCloser(bytes.NewReader(bb))
+	}
+
+	var n int64
+	if r.Body != nil && r.Body != http.NoBody {
+		if cfg.maxBodySize > 0 {
+			// Guard against unbounded bodies.
+			r.Body = http.MaxBytesReader(w, r.Body, cfg.maxBodySize)
+		}
+
+		src, err := bodyReader(cfg, r)
+		if err == nil {
+			n, err = readFrom(cfg, dst, w, r, src)
+		}
+		r.Body.Close()
+		if err != nil {
+			var mbe *http.MaxBytesError
+			if errors.As(err, &mbe) {
+				// Body is over the limit.
+				return n, ErrBodyTooLarge
+			}
+			if errors.Is(err, ErrReadTimeout) {
+				return n, err
+			}
+
+			// Body could not be fully read, no point in verifying.
+			return n, fmt.Errorf("%w: %v", ErrBodyRead, err)
+		}
+		if cfg.maxBodySize > 0 && n > cfg.maxBodySize {
+			// Decompressed body is over the limit.
+			return n, ErrBodyTooLarge
+		}
+		r.ContentLength = n
+	}
+
+	if cfg.requireBody && n == 0 {
+		return n, ErrEmptyBody
+	}
+
+	return n, nil
+}
+
+// Read from the source into the writer, within the read timeout if any.
+// The timeout is set as a read deadline on the connection where the server supports it,
+// as a server body can not be closed while a read on it is stuck.
+func readFrom(cfg *config, dst io.Writer, w http.ResponseWriter, r *http.Request, src io.Reader) (int64, error) {
+	if cfg.readTimeout <= 0 {
+		return io.Copy(dst, src)
+	}
+
+	rc := http.NewResponseController(w)
+	if err := rc.SetReadDeadline(time.Now().Add(cfg.readTimeout)); err != nil {
+		return readFromTimer(cfg, dst, r, src)
+	}
+
+	// Cut the read short when the request is cancelled.
+	stop := context.AfterFunc(r.Context(), func() {
+		rc.SetReadDeadline(time.Now())
+	})
+	n, err := io.Copy(dst, src)
+	stop()
+	if err != nil {
+		// The deadline is left passed, so closing the body does not wait on the rest of it.
+		if ctxErr := r.Context().Err(); ctxErr != nil {
+			return n, fmt.Errorf("%w: %w", ErrReadTimeout, ctxErr)
+		}
+		if errors.Is(err, os.ErrDeadlineExceeded) {
+			return n, ErrReadTimeout
+		}
+		return n, err
+	}
+	rc.SetReadDeadline(time.Time{})
+
+	return n, nil
+}
+
+// Read from the source into the writer, within the read timeout, without a read deadline.
+// On a timeout the body is closed to unblock the read, which carries on
+// into its own buffer so the passed writer is never written to afterwards.
+// The bytes read up to the timeout are counted, but not written.
+func readFromTimer(cfg *config, dst io.Writer, r *http.Request, src io.Reader) (int64, error) {
+	tmp := new(bytes.Buffer)
+	cr := &countingReader{r: src}
+	done := make(chan error, 1)
+	go func() {
+		_, err := tmp.ReadFrom(cr)
+		done <- err
+	}()
+
+	timer := time.NewTimer(cfg.readTimeout)
+	defer timer.Stop()
+
+	select {
+	case err := <-done:
+		if err != nil {
+			return int64(tmp.Len()), err
+		}
+		return tmp.WriteTo(dst)
+	case <-timer.C:
+		r.Body.Close()
+		return cr.n.Load(), ErrReadTimeout
+	case <-r.Context().Done():
+		r.Body.Close()
+		return cr.n.Load(), fmt.Errorf("%w: %w", ErrReadTimeout, r.Context().Err())
+	}
+}
+
+// Reader counting the bytes read, safe to check while reading.
+type countingReader struct {
+	r io.Reader
+	n atomic.Int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n.Add(int64(n))
+	return n, err
+}
+
+// Read the body, put it back, and verify it against the keys and HMACs.
+// The body is read into a pooled scratch buffer, then copied into the buffer,
+// so the restored body never shares memory with a buffer used by another request.
+// Without the body copy, it is only streamed through the hashers.
+// Returns the number of bytes read, even when the read fails.
+func verifyBody(cfg *config, buf *bytes.Buffer, keys []string, shop string, shmacs []string, w http.ResponseWriter, r *http.Request) (int64, error) {
+	if !cfg.bodyCopy {
+		return verifyBodyStream(cfg, keys, shmacs, w, r)
+	}
+
+	scratch := getBuffer()
+	defer putBuffer(scratch)
+	n, err := readBody(cfg, scratch, w, r)
+	buf.Grow(scratch.Len())
+	buf.Write(scratch.Bytes())
+	if err != nil {
+		// Restore what was read, for a next handler the failure is passed through to.
+		r.Body = NewRewindableBody(buf.Bytes())
+		r.ContentLength = int64(buf.Len())
+		return n, err
+	}
+	bb := buf.Bytes()
+	setDebugHMAC(cfg, w, keys, bb)
+
+	err = verifyRequestHMACs(cfg, keys, shop, shmacs, bb)
+	if err != nil && cfg.trailingNewline {
+		err = verifyTrailingNewline(cfg, buf, keys, shop, shmacs, err)
+	}
+	r.Body = NewRewindableBody(buf.Bytes())
+	r.ContentLength = int64(buf.Len())
+
+	return n, err
+}
@@ -0,0 +1,185 @@
+package http_shopify_webhook
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Empty bodies, all signed the same as an empty payload.
+func emptyBodyTests() []struct {
+	name string
+	body io.ReadCloser
+} {
+	return []struct {
+		name string
+		body io.ReadCloser
+	}{
+		{name: "nil", body: nil},
+		{name: "no body", body: http.NoBody},
+		{name: "zero length", body: io.NopCloser(bytes.NewReader(nil))},
+	}
+}
+
+// Test absent and empty bodies are verified as an empty payload.
+func TestEmptyBody(t *testing.T) {
+	for _, tt := range emptyBodyTests() {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", nil)
+		req.Body = tt.body
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		req.Header.Set("X-Shopify-Hmac-Sha256", sign("secret", ""))
+
+		if err := serveForError(req); err != nil {
+			t.Errorf("%s: expected no error got %v", tt.name, err)
+		}
+	}
+}
+
+// Test absent and empty bodies are rejected when a body is required.
+func TestWithRequireBody(t *testing.T) {
+	for _, tt := range emptyBodyTests() {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", nil)
+		req.Body = tt.body
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		req.Header.Set("X-Shopify-Hmac-Sha256", sign("secret", ""))
+
+		if err := serveForError(req, WithRequireBody()); !errors.Is(err, ErrEmptyBody) {
+			t.Errorf("%s: expected error %v got %v", tt.name, ErrEmptyBody, err)
+		}
+	}
+
+	// A body is still accepted.
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	if err := serveForError(req, WithRequireBody()); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
+
+// Body which blocks reads until closed.
+type blockingBody struct {
+	closed chan struct{}
+}
+
+func newBlockingBody() *blockingBody {
+	return &blockingBody{closed: make(chan struct{})}
+}
+
+func (b *blockingBody) Read(p []byte) (int, error) {
+	<-b.closed
+	return 0, io.ErrClosedPipe
+}
+
+func (b *blockingBody) Close() error {
+	select {
+	case <-b.closed:
+	default:
+		close(b.closed)
+	}
+	return nil
+}
+
+// Sets up a signed request with a body which never finishes.
+func newBlockingRequest() (*http.Request, *blockingBody) {
+	body := newBlockingBody()
+	req := newWebhookRequest(nil, "example.myshopify.com", sign("secret", ""))
+	req.Body = body
+
+	return req, body
+}
+
+// Test a stalled body read times out without running the next handler.
+func TestWithReadTimeout(t *testing.T) {
+	req, body := newBlockingRequest()
+	defer body.Close()
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithReadTimeout(10*time.Millisecond)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusRequestTimeout {
+		t.Errorf("expected status code %v got %v", http.StatusRequestTimeout, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+}
+
+// Test a client stalling mid-body on a real server times out.
+func TestReadTimeoutServer(t *testing.T) {
+	ran := false
+	srv := httptest.NewServer(WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithReadTimeout(50*time.Millisecond)))
+	defer srv.Close()
+
+	conn, err := net.Dial("tcp", srv.Listener.Addr().String())
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+
+	// Send part of the body, then stall.
+	body := `{"key":"value"}`
+	fmt.Fprintf(conn, "POST / HTTP/1.1\r\nHost: example\r\nContent-Length: %d\r\n", len(body))
+	fmt.Fprintf(conn, "X-Shopify-Shop-Domain: example.myshopify.com\r\nX-Shopify-Hmac-Sha256: %s\r\n\r\n", sign("secret", body))
+	io.WriteString(conn, body[:5])
+
+	resp, err := http.ReadResponse(bufio.NewReader(conn), nil)
+	if err != nil {
+		t.Fatalf("expected a response got %v", err)
+	}
+	defer resp.Body.Close()
+
+	if c := resp.StatusCode; c != http.StatusRequestTimeout {
+		t.Errorf("expected status code %v got %v", http.StatusRequestTimeout, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+}
+
+// Test a cancelled request context stops the body read.
+func TestReadTimeoutContextCancelled(t *testing.T) {
+	req, body := newBlockingRequest()
+	defer body.Close()
+
+	ctx, cancel := context.WithCancel(req.Context())
+	req = req.WithContext(ctx)
+	cancel()
+
+	err := serveForError(req, WithReadTimeout(time.Minute))
+	if !errors.Is(err, ErrReadTimeout) {
+		t.Errorf("expected error %v got %v", ErrReadTimeout, err)
+	}
+
+	if !errors.Is(err, context.Canceled) {
+		t.Errorf("expected error to include %v got %v", context.Canceled, err)
+	}
+}
+
+// Test a body read within the timeout is verified.
+func TestReadTimeoutWithinLimit(t *testing.T) {
+	rec, ran := serveWithOptions(`{"key":"value"}`, WithReadTimeout(time.Minute))
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
@@ -0,0 +1,79 @@
+package http_shopify_webhook
+
+import (
+	"crypto/hmac"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+)
+
+// Set whether the body is kept and restored for the next handler, enabled by default.
+// Disable it for handlers which never read the body, such as ones only using the
+// topic and shop, to stream the body through the hasher without buffering it.
+// When disabled, the next handler gets an empty body, and options working on the
+// body, such as `WithBodyTee` and `WithAsync`, see an empty body as well.
+// Example: `WebhookVerify("abc123", handler, WithBodyCopy(false))`.
+func WithBodyCopy(enabled bool) Option {
+	return func(cfg *config) {
+		cfg.bodyCopy = enabled
+	}
+}
+
+// Stream the body through a hasher for each key, then verify the HMACs against them.
+// The body is not kept, the next handler gets an empty one.
+// Returns the number of bytes read, even when the read fails.
+func verifyBodyStream(cfg *config, keys []string, shmacs []string, w http.ResponseWriter, r *http.Request) (int64, error) {
+	macs := make([]hash.Hash, len(keys))
+	ws := make([]io.Writer, len(keys))
+	for i, key := range keys {
+		macs[i] = hmac.New(cfg.hashFunc, []byte(key))
+		ws[i] = macs[i]
+	}
+
+	n, err := readBody(cfg, io.MultiWriter(ws...), w, r)
+	r.Body = http.NoBody
+	r.ContentLength = 0
+	if err != nil {
+		return n, err
+	}
+
+	return n, verifyMACs(cfg, macs, shmacs, w)
+}
+
+// Verify the HMACs against the digests of the hashers.
+// All are compared, so the time taken does not reveal which one matched.
+func verifyMACs(cfg *config, macs []hash.Hash, shmacs []string, w http.ResponseWriter) error {
+	if len(macs) == 0 {
+		return ErrInvalidSignature
+	}
+
+	sums := make([][]byte, len(macs))
+	for i, mac := range macs {
+		sums[i] = mac.Sum(nil)
+		if cfg.debugHeaders {
+			w.Header().Add(DebugHMACHeader, base64.StdEncoding.EncodeToString(sums[i]))
+		}
+	}
+
+	return verifyAny(shmacs, func(shmac string) error {
+		dec, err := cfg.decodeHMAC(shmac, macs[0].Size())
+		if err != nil {
+			// Not a valid base64 string, or not a digest.
+			return fmt.Errorf("%w: malformed HMAC: %v", ErrInvalidSignature, err)
+		}
+
+		ok := false
+		for _, sum := range sums {
+			if hmac.Equal(sum, dec) {
+				ok = true
+			}
+		}
+		if !ok {
+			return ErrInvalidSignature
+		}
+
+		return nil
+	})
+}
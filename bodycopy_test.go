@@ -0,0 +1,115 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs a request signed with the key through the verifier, returning the body the handler read.
+func serveBodyCopy(key string, opts ...Option) (*httptest.ResponseRecorder, string, bool) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign(key, body))
+
+	ran := false
+	var got string
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		bb, _ := io.ReadAll(r.Body)
+		got = string(bb)
+	}, opts...).ServeHTTP(rec, req)
+
+	return rec, got, ran
+}
+
+// Test the body is restored by default.
+func TestBodyCopyDefault(t *testing.T) {
+	for _, opts := range [][]Option{nil, {WithBodyCopy(true)}} {
+		_, got, ran := serveBodyCopy("secret", opts...)
+		if !ran || got != `{"key":"value"}` {
+			t.Errorf("expected handler to read the body got %q", got)
+		}
+	}
+}
+
+// Test the body is verified without being kept when disabled.
+func TestWithBodyCopyDisabled(t *testing.T) {
+	rec, got, ran := serveBodyCopy("secret", WithBodyCopy(false))
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+
+	if got != "" {
+		t.Errorf("expected handler to get an empty body got %q", got)
+	}
+
+	rec, _, ran = serveBodyCopy("other", WithBodyCopy(false))
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+}
+
+// Test streaming applies the same checks as buffering.
+func TestBodyCopyDisabledChecks(t *testing.T) {
+	body := `{"key":"value"}`
+	tests := []struct {
+		name string
+		req  *http.Request
+		opts []Option
+		err  error
+	}{
+		{
+			name: "malformed hmac",
+			req:  newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", "not base64!"),
+			err:  ErrInvalidSignature,
+		},
+		{
+			name: "too large",
+			req:  newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)),
+			opts: []Option{WithMaxBodySize(5)},
+			err:  ErrBodyTooLarge,
+		},
+		{
+			name: "empty",
+			req:  newWebhookRequest(nil, "example.myshopify.com", sign("secret", "")),
+			opts: []Option{WithRequireBody()},
+			err:  ErrEmptyBody,
+		},
+		{
+			name: "unreadable",
+			req:  newWebhookRequest(&errReader{}, "example.myshopify.com", sign("secret", body)),
+			err:  ErrBodyRead,
+		},
+	}
+
+	for _, tt := range tests {
+		if err := serveForError(tt.req, append(tt.opts, WithBodyCopy(false))...); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// Test each of several keys are tried while streaming.
+func TestBodyCopyDisabledMulti(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("old", body))
+
+	rec := httptest.NewRecorder()
+	WebhookVerifyMulti([]string{"new", "old"}, func(w http.ResponseWriter, r *http.Request) {}, WithBodyCopy(false)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+}
@@ -0,0 +1,34 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"sync"
+)
+
+// Buffers over this capacity are not returned to the pool,
+// so one large webhook does not keep its memory around.
+const maxPooledBufferSize = 1 << 20
+
+// Pool of scratch buffers for reading webhook bodies.
+// Only used while reading, the body handed on is always copied out of it.
+// The HMAC hashers are keyed by the secret, so are created per request instead.
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Get an empty buffer from the pool.
+func getBuffer() *bytes.Buffer {
+	return bufferPool.Get().(*bytes.Buffer)
+}
+
+// Reset the buffer and return it to the pool.
+// The buffer must no longer be used by anything once returned.
+func putBuffer(buf *bytes.Buffer) {
+	if buf.Cap() > maxPooledBufferSize {
+		return
+	}
+	buf.Reset()
+	bufferPool.Put(buf)
+}
@@ -0,0 +1,126 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Test pooled buffers come back empty.
+func TestPutBufferResets(t *testing.T) {
+	buf := getBuffer()
+	buf.WriteString(`{"key":"value"}`)
+	putBuffer(buf)
+
+	for i := 0; i < 10; i++ {
+		b := getBuffer()
+		if b.Len() != 0 {
+			t.Errorf("expected pooled buffer to be empty got %q", b.String())
+		}
+		putBuffer(b)
+	}
+}
+
+// Test many concurrent requests each verify and see their own body.
+func TestConcurrentRequests(t *testing.T) {
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		bb, _ := ioutil.ReadAll(r.Body)
+		w.Write(bb)
+	})
+	h := WebhookVerify("secret", nh)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			// Vary the size so buffers of different lengths are reused.
+			body := fmt.Sprintf(`{"id":%d,"note":"%s"}`, i, strings.Repeat("x", i*10))
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)))
+
+			if c := rec.Code; c != http.StatusOK {
+				t.Errorf("expected status code %v got %v", http.StatusOK, c)
+			}
+			if b := rec.Body.String(); b != body {
+				t.Errorf("expected body %q got %q", body, b)
+			}
+		}(i)
+	}
+	wg.Wait()
+}
+
+// Test a handler which keeps the body, to read once it returns, does not see other requests.
+func TestRetainedBody(t *testing.T) {
+	var kept []io.Reader
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		kept = append(kept, r.Body)
+	})
+
+	bodies := []string{`{"id":1}`, `{"id":2,"other":"value"}`, `{"id":3}`}
+	for _, body := range bodies {
+		h.ServeHTTP(httptest.NewRecorder(), newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)))
+	}
+
+	for i, body := range bodies {
+		bb, _ := ioutil.ReadAll(kept[i])
+		if b := string(bb); b != body {
+			t.Errorf("expected kept body %q got %q", body, b)
+		}
+	}
+}
+
+// Benchmark a verified request through the middleware.
+func BenchmarkWebhookVerify(b *testing.B) {
+	body := `{"key":"value"}`
+	hmac := sign("secret", body)
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", hmac))
+	}
+}
+
+// Benchmark a verified request with a large body through the middleware.
+func BenchmarkWebhookVerifyLarge(b *testing.B) {
+	body := strings.Repeat("x", 1<<20)
+	hmac := sign("secret", body)
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {})
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(body)))
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newWebhookRequest(strings.NewReader(body), "example.myshopify.com", hmac))
+	}
+}
+
+// Benchmark a request with a signature which does not match.
+func BenchmarkWebhookVerifyInvalidSignature(b *testing.B) {
+	body := `{"key":"value"}`
+	hmac := sign("other", body)
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", hmac))
+	}
+}
+
+// Benchmark a request rejected on its headers, with a large body which should never be read.
+func BenchmarkWebhookVerifyHeaderRejected(b *testing.B) {
+	body := strings.Repeat("x", 1<<20)
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {})
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h.ServeHTTP(httptest.NewRecorder(), newWebhookRequest(strings.NewReader(body), "example.myshopify.com", ""))
+	}
+}
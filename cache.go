@@ -0,0 +1,43 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+)
+
+// Middleware which buffers the body into the request context early in the chain.
+// Place it before middleware which consume the body, such as request loggers,
+// and the verifier reads the cached body instead of the possibly drained stream.
+// Bodies over `DefaultMaxBodySize` are rejected with a 413.
+// Example: `CacheBody(logRequests(WebhookVerify("abc123", handler)))`.
+func CacheBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var bb []byte
+		if r.Body != nil && r.Body != http.NoBody {
+			var err error
+			bb, err = io.ReadAll(http.MaxBytesReader(w, r.Body, DefaultMaxBodySize))
+			r.Body.Close()
+			if err != nil {
+				var mbe *http.MaxBytesError
+				if errors.As(err, &mbe) {
+					http.Error(w, "Webhook body too large", http.StatusRequestEntityTooLarge)
+					return
+				}
+				http.Error(w, "Unable to read webhook body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), bodyKey, bb))
+		r.Body = NewRewindableBody(bb)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Get the body cached by `CacheBody` from the context.
+func cachedBody(ctx context.Context) ([]byte, bool) {
+	bb, ok := ctx.Value(bodyKey).([]byte)
+	return bb, ok
+}
@@ -0,0 +1,86 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Middleware which drains the body, like a careless request logger.
+func drainBody(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.ReadAll(r.Body)
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Test the verifier reads the cached body after the stream was drained.
+func TestCacheBody(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	var got string
+	rec := httptest.NewRecorder()
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		bb, _ := io.ReadAll(r.Body)
+		got = string(bb)
+	})
+	CacheBody(drainBody(h)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if got != body {
+		t.Errorf("expected handler to read body %q got %q", body, got)
+	}
+}
+
+// Test a drained body fails to verify without the cache.
+func TestCacheBodyMissing(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	rec := httptest.NewRecorder()
+	drainBody(WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {})).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test the cached body is still limited by the verifier.
+func TestCacheBodyMaxBodySize(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	rec := httptest.NewRecorder()
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMaxBodySize(10))
+	CacheBody(drainBody(h)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code %v got %v", http.StatusRequestEntityTooLarge, c)
+	}
+}
+
+// Test an unreadable body is rejected by the cache.
+func TestCacheBodyReadError(t *testing.T) {
+	req := newWebhookRequest(&errReader{}, "example.myshopify.com", "")
+
+	ran := false
+	rec := httptest.NewRecorder()
+	CacheBody(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	})).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+}
@@ -0,0 +1,48 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Trust the header, such as `X-Forwarded-For`, for the client IP behind a proxy.
+// The last address in the header is used, the one the proxy in front of the server
+// added, as any earlier ones are sent by the client. Falls back to the remote address
+// when it is missing or invalid. Only enable behind a proxy which sets the header.
+// Example: `WebhookVerify("abc123", handler, WithTrustedProxyHeader("X-Forwarded-For"))`.
+func WithTrustedProxyHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.proxyHeader = name
+	}
+}
+
+// Get the client IP of the verified webhook from the request context.
+// Read from the trusted proxy header if set, otherwise the remote address.
+// Example: `ip, ok := ClientIPFromContext(r.Context())`.
+func ClientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPKey).(string)
+	return ip, ok
+}
+
+// Get the client IP of the request.
+func clientIP(cfg *config, r *http.Request) string {
+	if cfg.proxyHeader != "" {
+		if vv := r.Header.Values(cfg.proxyHeader); len(vv) > 0 {
+			// Each proxy appends the address it received from, so the last is ours.
+			v := vv[len(vv)-1]
+			if ip := net.ParseIP(strings.TrimSpace(v[strings.LastIndex(v, ",")+1:])); ip != nil {
+				return ip.String()
+			}
+		}
+	}
+
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		// No port in the address.
+		return r.RemoteAddr
+	}
+
+	return host
+}
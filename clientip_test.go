@@ -0,0 +1,70 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs a signed request with the forwarding header through the verifier, returning the client IP.
+func serveClientIP(forwarded string, opts ...Option) (string, bool) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.RemoteAddr = "10.0.0.1:4321"
+	if forwarded != "" {
+		req.Header.Set("X-Forwarded-For", forwarded)
+	}
+
+	var ip string
+	var ok bool
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ip, ok = ClientIPFromContext(r.Context())
+	}, opts...).ServeHTTP(httptest.NewRecorder(), req)
+
+	return ip, ok
+}
+
+// Test the remote address is used for a direct request.
+func TestClientIPDirect(t *testing.T) {
+	ip, ok := serveClientIP("", WithTrustedProxyHeader("X-Forwarded-For"))
+	if !ok || ip != "10.0.0.1" {
+		t.Errorf("expected client IP %q got %q", "10.0.0.1", ip)
+	}
+}
+
+// Test the last forwarded address is used with the option.
+func TestWithTrustedProxyHeader(t *testing.T) {
+	ip, _ := serveClientIP("203.0.113.7", WithTrustedProxyHeader("X-Forwarded-For"))
+	if ip != "203.0.113.7" {
+		t.Errorf("expected client IP %q got %q", "203.0.113.7", ip)
+	}
+
+	// A leading address sent by the client is not trusted.
+	ip, _ = serveClientIP("198.51.100.9, 203.0.113.7", WithTrustedProxyHeader("X-Forwarded-For"))
+	if ip != "203.0.113.7" {
+		t.Errorf("expected client IP %q got %q", "203.0.113.7", ip)
+	}
+
+	// An invalid address falls back to the remote address.
+	ip, _ = serveClientIP("not-an-ip", WithTrustedProxyHeader("X-Forwarded-For"))
+	if ip != "10.0.0.1" {
+		t.Errorf("expected client IP %q got %q", "10.0.0.1", ip)
+	}
+}
+
+// Test the forwarding header is not trusted by default.
+func TestTrustedProxyHeaderDefault(t *testing.T) {
+	ip, _ := serveClientIP("203.0.113.7")
+	if ip != "10.0.0.1" {
+		t.Errorf("expected client IP %q got %q", "10.0.0.1", ip)
+	}
+}
+
+// Test the client IP is not set for requests which did not verify.
+func TestClientIPFromContextMissing(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/", nil)
+	if _, ok := ClientIPFromContext(req.Context()); ok {
+		t.Errorf("expected no client IP got one")
+	}
+}
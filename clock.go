@@ -0,0 +1,44 @@
+package http_shopify_webhook
+
+import "time"
+
+// Use the function for the current time, instead of `time.Now`, such as a fake clock in tests.
+// Feeds every time based check, including `WithMaxAge`, and the windows of the built-in
+// `MemoryDedupStore` and `TokenBucketLimiter` when they are passed as options.
+// Example: `WebhookVerify("abc123", handler, WithMaxAge(time.Minute), WithClock(clock.Now))`.
+func WithClock(now func() time.Time) Option {
+	return func(cfg *config) {
+		cfg.clock = now
+	}
+}
+
+// Implemented by the built-in stores which depend on the current time.
+type clockSetter interface {
+	setClock(now func() time.Time)
+}
+
+// Apply the clock, if set, to the config and its time based stores.
+func applyClock(cfg *config) {
+	if cfg.clock == nil {
+		return
+	}
+
+	cfg.now = cfg.clock
+	for _, v := range []any{cfg.dedup, cfg.rateLimiter} {
+		if cs, ok := v.(clockSetter); ok {
+			cs.setClock(cfg.clock)
+		}
+	}
+}
+
+func (s *MemoryDedupStore) setClock(now func() time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.now = now
+}
+
+func (l *TokenBucketLimiter) setClock(now func() time.Time) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.now = now
+}
@@ -0,0 +1,93 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Clock which only moves when advanced.
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.now = c.now.Add(d)
+}
+
+// Test advancing the clock expires a replay protected webhook.
+func TestWithClockExpires(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)}
+	body := `{"key":"value"}`
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMaxAge(5*time.Minute), WithClock(clock.Now))
+
+	send := func() int {
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+		req.Header.Set("X-Shopify-Triggered-At", "2019-04-01T12:00:00Z")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+		return rec.Code
+	}
+
+	if c := send(); c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	clock.Advance(6 * time.Minute)
+	if c := send(); c != http.StatusBadRequest {
+		t.Errorf("expected status code %v once expired got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test the clock is fed to the built-in stores.
+func TestWithClockStores(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)}
+	store := NewMemoryDedupStore()
+	limiter := NewTokenBucketLimiter(1, 1)
+
+	// The clock applies regardless of the order of the options.
+	cfg := newConfig([]Option{WithClock(clock.Now), WithDeduplication(store, time.Minute), WithRateLimit(limiter)})
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	if err := checkRateLimit(cfg, req, "example.myshopify.com"); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	if err := checkRateLimit(cfg, req, "example.myshopify.com"); !errors.Is(err, ErrRateLimited) {
+		t.Errorf("expected error %v got %v", ErrRateLimited, err)
+	}
+
+	ctx := context.Background()
+	store.MarkIfAbsent(ctx, "b54557e4", time.Minute)
+	clock.Advance(time.Minute)
+
+	if marked, _ := store.MarkIfAbsent(ctx, "b54557e4", time.Minute); !marked {
+		t.Errorf("expected webhook ID to expire with the clock")
+	}
+
+	if err := checkRateLimit(cfg, req, "example.myshopify.com"); err != nil {
+		t.Errorf("expected rate limit to refill with the clock got %v", err)
+	}
+}
+
+// Test the default clock is the system time.
+func TestClockDefault(t *testing.T) {
+	cfg := newConfig(nil)
+	if d := time.Since(cfg.now()); d < 0 || d > time.Minute {
+		t.Errorf("expected the current time got %v", cfg.now())
+	}
+}
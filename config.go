@@ -0,0 +1,75 @@
+package http_shopify_webhook
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+)
+
+// Returned by `New` for a config without a secret.
+var ErrMissingSecret = errors.New("missing webhook secret")
+
+// Settings for the verifier, for loading from files or the environment.
+// An alternative to the options, zero values keep the defaults.
+type Config struct {
+	// Secret key for the Shopify app, required.
+	Secret string `json:"secret"`
+
+	// Maximum bytes of body to read, zero for the default and negative for no limit.
+	MaxBodySize int64 `json:"max_body_size,omitempty"`
+
+	// Headers holding the HMAC and the shop domain.
+	HMACHeader string `json:"hmac_header,omitempty"`
+	ShopHeader string `json:"shop_header,omitempty"`
+
+	// Shops webhooks are accepted from, empty for any.
+	AllowedShops []string `json:"allowed_shops,omitempty"`
+}
+
+// Build the verify middleware from the config.
+// Returns an error if the config is not usable, such as without a secret.
+// Example: `mw, err := New(Config{Secret: "abc123"})`.
+func New(cfg Config) (Middleware, error) {
+	if cfg.Secret == "" {
+		return nil, ErrMissingSecret
+	}
+
+	opts := cfg.options()
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return WebhookVerify(cfg.Secret, fn, opts...)
+	}, nil
+}
+
+// Build the verify middleware with the secret key from the environment variable,
+// such as `SHOPIFY_WEBHOOK_SECRET`. Returns an error if it is unset or empty.
+// Example: `mw, err := WebhookVerifyFromEnv("SHOPIFY_WEBHOOK_SECRET")`.
+func WebhookVerifyFromEnv(name string, opts ...Option) (Middleware, error) {
+	key := os.Getenv(name)
+	if key == "" {
+		return nil, fmt.Errorf("%w: %s is not set", ErrMissingSecret, name)
+	}
+
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return WebhookVerify(key, fn, opts...)
+	}, nil
+}
+
+// Options matching the settings of the config.
+func (cfg Config) options() []Option {
+	var opts []Option
+	if cfg.MaxBodySize != 0 {
+		opts = append(opts, WithMaxBodySize(cfg.MaxBodySize))
+	}
+	if cfg.HMACHeader != "" {
+		opts = append(opts, WithHMACHeader(cfg.HMACHeader))
+	}
+	if cfg.ShopHeader != "" {
+		opts = append(opts, WithShopHeader(cfg.ShopHeader))
+	}
+	if len(cfg.AllowedShops) > 0 {
+		opts = append(opts, WithAllowedShops(cfg.AllowedShops...))
+	}
+
+	return opts
+}
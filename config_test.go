@@ -0,0 +1,159 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Test a valid config builds a working middleware.
+func TestNew(t *testing.T) {
+	mw, err := New(Config{
+		Secret:       "secret",
+		HMACHeader:   "X-Proxy-Hmac",
+		AllowedShops: []string{"example.myshopify.com"},
+	})
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	body := `{"key":"value"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Proxy-Hmac", sign("secret", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	mw(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Test the config settings are applied.
+func TestNewOptions(t *testing.T) {
+	cfg := newConfig(Config{
+		Secret:       "secret",
+		MaxBodySize:  -1,
+		ShopHeader:   "X-Proxy-Shop",
+		AllowedShops: []string{"example.myshopify.com"},
+	}.options())
+
+	if s := cfg.maxBodySize; s != -1 {
+		t.Errorf("expected max body size of %v got %v", -1, s)
+	}
+
+	if h := cfg.shopHeader; h != "X-Proxy-Shop" {
+		t.Errorf("expected shop header %q got %q", "X-Proxy-Shop", h)
+	}
+
+	if !cfg.allowedShops["example.myshopify.com"] {
+		t.Errorf("expected shop to be allowed but was not")
+	}
+
+	// Zero values keep the defaults.
+	cfg = newConfig(Config{Secret: "secret"}.options())
+	if s := cfg.maxBodySize; s != DefaultMaxBodySize {
+		t.Errorf("expected max body size of %v got %v", DefaultMaxBodySize, s)
+	}
+
+	if h := cfg.hmacHeader; h != DefaultHMACHeader {
+		t.Errorf("expected hmac header %q got %q", DefaultHMACHeader, h)
+	}
+}
+
+// Test a config without a secret is rejected.
+func TestNewMissingSecret(t *testing.T) {
+	mw, err := New(Config{})
+	if !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("expected error %v got %v", ErrMissingSecret, err)
+	}
+
+	if mw != nil {
+		t.Errorf("expected no middleware got one")
+	}
+}
+
+// Test the config can be loaded from JSON.
+func TestConfigJSON(t *testing.T) {
+	in := Config{
+		Secret:       "secret",
+		MaxBodySize:  1 << 20,
+		HMACHeader:   "X-Proxy-Hmac",
+		ShopHeader:   "X-Proxy-Shop",
+		AllowedShops: []string{"example.myshopify.com"},
+	}
+
+	bb, err := json.Marshal(in)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	var out Config
+	if err := json.Unmarshal(bb, &out); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("expected config %+v got %+v", in, out)
+	}
+
+	// Keys are snake cased.
+	if err := json.Unmarshal([]byte(`{"secret":"abc123","max_body_size":10}`), &out); err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	if out.Secret != "abc123" || out.MaxBodySize != 10 {
+		t.Errorf("expected secret and max body size to be loaded got %+v", out)
+	}
+}
+
+// Test the secret is read from the environment.
+func TestWebhookVerifyFromEnv(t *testing.T) {
+	t.Setenv("TEST_SHOPIFY_WEBHOOK_SECRET", "secret")
+
+	mw, err := WebhookVerifyFromEnv("TEST_SHOPIFY_WEBHOOK_SECRET", WithMaxBodySize(1<<10))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	body := `{"key":"value"}`
+	rec := httptest.NewRecorder()
+	mw(func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rec, newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)))
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+}
+
+// Test an unset or empty environment variable is an error.
+func TestWebhookVerifyFromEnvUnset(t *testing.T) {
+	t.Setenv("TEST_SHOPIFY_WEBHOOK_SECRET", "")
+
+	for _, name := range []string{"TEST_SHOPIFY_WEBHOOK_SECRET", "TEST_SHOPIFY_WEBHOOK_SECRET_UNSET"} {
+		mw, err := WebhookVerifyFromEnv(name)
+		if !errors.Is(err, ErrMissingSecret) {
+			t.Errorf("expected error %v got %v", ErrMissingSecret, err)
+		}
+
+		if err != nil && !strings.Contains(err.Error(), name) {
+			t.Errorf("expected error to name %s got %v", name, err)
+		}
+
+		if mw != nil {
+			t.Errorf("expected no middleware got one")
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package http_shopify_webhook
+
+import "context"
+
+// Key type for values stored in the request context.
+type contextKey int
+
+const (
+	// Verified shop domain.
+	shopKey contextKey = iota
+
+	// Topic of the verified webhook.
+	topicKey
+
+	// Marker of a verified request.
+	verifiedKey
+
+	// Body cached by `CacheBody`.
+	bodyKey
+
+	// Client IP of the verified webhook.
+	clientIPKey
+
+	// API version of the verified webhook.
+	apiVersionKey
+
+	// Reason a passed through request failed verification.
+	verifyErrorKey
+
+	// Webhook ID marked as delivered by the dedup store.
+	dedupKey
+
+	// Shop domain from the configured header, before verification.
+	claimedShopKey
+)
+
+// Get the verified shop domain from the request context.
+// Only set once `WebhookVerify` has verified the request.
+// Example: `shop, ok := ShopFromContext(r.Context())`.
+func ShopFromContext(ctx context.Context) (string, bool) {
+	shop, ok := ctx.Value(shopKey).(string)
+	return shop, ok
+}
+
+// Get the shop domain the request claims to be from, read from the configured shop header.
+// Set for trace hooks, which run before verification, so it is not to be trusted.
+// Example: `shop, ok := ClaimedShopFromContext(r.Context())`.
+func ClaimedShopFromContext(ctx context.Context) (string, bool) {
+	shop, ok := ctx.Value(claimedShopKey).(string)
+	return shop, ok
+}
+
+// Get the topic of the verified webhook from the request context, such as `orders/create`.
+// Only set once `WebhookVerify` has verified a request with a `X-Shopify-Topic` header.
+// Example: `topic, ok := TopicFromContext(r.Context())`.
+func TopicFromContext(ctx context.Context) (string, bool) {
+	topic, ok := ctx.Value(topicKey).(string)
+	return topic, ok
+}
+
+// Get the API version of the verified webhook payload from the request context, such as `2024-01`.
+// Only set once `WebhookVerify` has verified a request with a `X-Shopify-API-Version` header,
+// otherwise the version is unknown.
+// Example: `version, ok := APIVersionFromContext(r.Context())`.
+func APIVersionFromContext(ctx context.Context) (string, bool) {
+	version, ok := ctx.Value(apiVersionKey).(string)
+	return version, ok
+}
+
+// Get the reason the request failed verification, for requests passed through
+// by `WithPassThroughOnFailure` or `WithAllowInsecure`. Nil for verified requests.
+// Example: `if err := VerifyErrorFromContext(r.Context()); err != nil { ... }`.
+func VerifyErrorFromContext(ctx context.Context) error {
+	err, _ := ctx.Value(verifyErrorKey).(error)
+	return err
+}
+
+// Check if the request was verified by `WebhookVerify`.
+// Lets later middleware confirm verification ran, rather than trusting the headers.
+// Example: `if !IsVerified(r.Context()) { ... }`.
+func IsVerified(ctx context.Context) bool {
+	verified, _ := ctx.Value(verifiedKey).(bool)
+	return verified
+}
@@ -0,0 +1,155 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the verified shop is available to the next handler.
+func TestShopFromContext(t *testing.T) {
+	body := `{"key":"value"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", sign("secret", body))
+
+	var shop string
+	var found bool
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		shop, found = ShopFromContext(r.Context())
+	})
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nh).ServeHTTP(rec, req)
+
+	if !found {
+		t.Errorf("expected shop to be in context but it was not")
+	}
+
+	if shop != "example.myshopify.com" {
+		t.Errorf("expected shop %q got %q", "example.myshopify.com", shop)
+	}
+}
+
+// Test the shop is missing from a context which was never verified.
+func TestShopFromContextMissing(t *testing.T) {
+	if _, ok := ShopFromContext(context.Background()); ok {
+		t.Errorf("expected shop to be missing from context but it was found")
+	}
+}
+
+// Test the next handler can dispatch on the verified topic.
+func TestTopicFromContext(t *testing.T) {
+	var handled []string
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		topic, _ := TopicFromContext(r.Context())
+		switch topic {
+		case "orders/create":
+			handled = append(handled, "order")
+		case "app/uninstalled":
+			handled = append(handled, "uninstall")
+		default:
+			handled = append(handled, "unknown")
+		}
+	})
+	h := WebhookVerify("secret", nh)
+
+	for _, topic := range []string{"app/uninstalled", "orders/create", ""} {
+		body := `{"key":"value"}`
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+		if topic != "" {
+			req.Header.Set("X-Shopify-Topic", topic)
+		}
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	exp := []string{"uninstall", "order", "unknown"}
+	if len(handled) != len(exp) {
+		t.Fatalf("expected %v webhooks handled got %v", len(exp), len(handled))
+	}
+	for i := range exp {
+		if handled[i] != exp[i] {
+			t.Errorf("expected webhook %v to be handled as %q got %q", i, exp[i], handled[i])
+		}
+	}
+}
+
+// Test later middleware can check the request was verified.
+func TestIsVerified(t *testing.T) {
+	// Middleware which only lets verified requests through.
+	var passed []bool
+	requireVerified := func(next http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if !IsVerified(r.Context()) {
+				passed = append(passed, false)
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			passed = append(passed, true)
+			next(w, r)
+		}
+	}
+	nh := func(w http.ResponseWriter, r *http.Request) {}
+
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	// Behind the verifier.
+	WebhookVerify("secret", requireVerified(nh)).ServeHTTP(httptest.NewRecorder(), req)
+
+	// Without the verifier, even with the headers present.
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	rec := httptest.NewRecorder()
+	requireVerified(nh).ServeHTTP(rec, req)
+
+	if len(passed) != 2 || !passed[0] || passed[1] {
+		t.Errorf("expected only the verified request to pass got %v", passed)
+	}
+
+	if c := rec.Code; c != http.StatusForbidden {
+		t.Errorf("expected status code %v got %v", http.StatusForbidden, c)
+	}
+}
+
+// Test the API version is available to the handler from the context.
+func TestAPIVersionFromContext(t *testing.T) {
+	var version string
+	var found bool
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		version, found = APIVersionFromContext(r.Context())
+	})
+
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-API-Version", "2024-01")
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if !found || version != "2024-01" {
+		t.Errorf("expected API version %q got %q", "2024-01", version)
+	}
+
+	// Missing header is an unknown version, not a failure.
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if found || version != "" {
+		t.Errorf("expected no API version got %q", version)
+	}
+}
+
+// Test the API version is not set for unverified requests.
+func TestAPIVersionFromContextUnverified(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", nil)
+	req.Header.Set("X-Shopify-API-Version", "2024-01")
+
+	if _, ok := APIVersionFromContext(req.Context()); ok {
+		t.Errorf("expected no API version for an unverified request")
+	}
+}
@@ -0,0 +1,44 @@
+package http_shopify_webhook
+
+import (
+	"encoding/base64"
+	"net/http"
+	"strconv"
+)
+
+// Response headers set by `WithDebugHeaders`.
+const (
+	DebugVerifiedHeader = "X-Webhook-Verified"
+	DebugHMACHeader     = "X-Webhook-Computed-Hmac"
+)
+
+// Set response headers with the verification result and the computed HMAC,
+// to compare with what Shopify sent while debugging failing webhooks.
+// Unsafe for production: the computed HMAC is a valid signature for the body,
+// and is returned to whoever sent the request.
+// Example: `WebhookVerify("abc123", handler, WithDebugHeaders())`.
+func WithDebugHeaders() Option {
+	return func(cfg *config) {
+		cfg.debugHeaders = true
+	}
+}
+
+// Set the computed HMAC of the body for each key, if enabled.
+func setDebugHMAC(cfg *config, w http.ResponseWriter, keys []string, bb []byte) {
+	if !cfg.debugHeaders {
+		return
+	}
+
+	for _, key := range keys {
+		w.Header().Add(DebugHMACHeader, base64.StdEncoding.EncodeToString(digestHash(cfg.hashFunc, key, bb)))
+	}
+}
+
+// Set the verification result, if enabled.
+func setDebugVerified(cfg *config, w http.ResponseWriter, err error) {
+	if !cfg.debugHeaders {
+		return
+	}
+
+	w.Header().Set(DebugVerifiedHeader, strconv.FormatBool(err == nil))
+}
@@ -0,0 +1,58 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs a request signed with the key through the verifier, returning the response.
+func serveDebug(key string, opts ...Option) *httptest.ResponseRecorder {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign(key, body))
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, opts...).ServeHTTP(rec, req)
+
+	return rec
+}
+
+// Test the debug headers are set with the option.
+func TestWithDebugHeaders(t *testing.T) {
+	want := sign("secret", `{"key":"value"}`)
+
+	rec := serveDebug("secret", WithDebugHeaders())
+	if v := rec.Header().Get(DebugVerifiedHeader); v != "true" {
+		t.Errorf("expected verified header %q got %q", "true", v)
+	}
+
+	if h := rec.Header().Get(DebugHMACHeader); h != want {
+		t.Errorf("expected computed hmac header %q got %q", want, h)
+	}
+
+	// A mismatch still shows the HMAC which was expected.
+	rec = serveDebug("other", WithDebugHeaders())
+	if v := rec.Header().Get(DebugVerifiedHeader); v != "false" {
+		t.Errorf("expected verified header %q got %q", "false", v)
+	}
+
+	if h := rec.Header().Get(DebugHMACHeader); h != want {
+		t.Errorf("expected computed hmac header %q got %q", want, h)
+	}
+}
+
+// Test the debug headers are not set by default.
+func TestDebugHeadersDefault(t *testing.T) {
+	for _, key := range []string{"secret", "other"} {
+		rec := serveDebug(key)
+
+		if v := rec.Header().Get(DebugVerifiedHeader); v != "" {
+			t.Errorf("expected no verified header got %q", v)
+		}
+
+		if h := rec.Header().Get(DebugHMACHeader); h != "" {
+			t.Errorf("expected no computed hmac header got %q", h)
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package http_shopify_webhook
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Decompress gzip encoded bodies before verifying, such as ones compressed by a proxy.
+// Shopify signs the uncompressed payload, which is also what the next handler receives.
+// The max body size applies to the decompressed body, guarding against decompression bombs.
+// Example: `WebhookVerify("abc123", handler, WithDecompression())`.
+func WithDecompression() Option {
+	return func(cfg *config) {
+		cfg.decompress = true
+	}
+}
+
+// Reader of the request body, decompressed if enabled and gzip encoded.
+// The encoding header is removed, as the restored body is no longer encoded.
+func bodyReader(cfg *config, r *http.Request) (io.Reader, error) {
+	if !cfg.decompress || !isGzip(r.Header.Get("Content-Encoding")) {
+		return r.Body, nil
+	}
+
+	gz, err := gzip.NewReader(r.Body)
+	if err != nil {
+		return nil, err
+	}
+	r.Header.Del("Content-Encoding")
+
+	if cfg.maxBodySize > 0 {
+		// Read one byte past the limit, to know when it is exceeded.
+		return io.LimitReader(gz, cfg.maxBodySize+1), nil
+	}
+
+	return gz, nil
+}
+
+// Check if the content encoding is gzip.
+func isGzip(enc string) bool {
+	enc = strings.TrimSpace(enc)
+
+	return strings.EqualFold(enc, "gzip") || strings.EqualFold(enc, "x-gzip")
+}
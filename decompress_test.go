@@ -0,0 +1,99 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Compress the body with gzip.
+func gzipBody(body string) *bytes.Buffer {
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	gz.Write([]byte(body))
+	gz.Close()
+
+	return buf
+}
+
+// Sets up a gzip encoded request signed over the uncompressed body.
+func newGzipRequest(body string) *http.Request {
+	req := newWebhookRequest(gzipBody(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	return req
+}
+
+// Test a gzip encoded body is decompressed, verified, and restored.
+func TestWithDecompression(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newGzipRequest(body)
+
+	var got string
+	var enc string
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		bb, _ := io.ReadAll(r.Body)
+		got = string(bb)
+		enc = r.Header.Get("Content-Encoding")
+	}, WithDecompression()).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if got != body {
+		t.Errorf("expected restored body %q got %q", body, got)
+	}
+
+	if enc != "" {
+		t.Errorf("expected no content encoding got %q", enc)
+	}
+}
+
+// Test a gzip encoded body fails without the option, as the compressed bytes are verified.
+func TestDecompressionDisabled(t *testing.T) {
+	if err := serveForError(newGzipRequest(`{"key":"value"}`)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+}
+
+// Test a body which is not encoded is verified as is with the option.
+func TestDecompressionPlainBody(t *testing.T) {
+	rec, ran := serveWithOptions(`{"key":"value"}`, WithDecompression())
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Test the max body size applies to the decompressed body.
+func TestDecompressionMaxBodySize(t *testing.T) {
+	body := strings.Repeat("a", 1<<16)
+	req := newGzipRequest(body)
+
+	err := serveForError(req, WithDecompression(), WithMaxBodySize(1<<10))
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected error %v got %v", ErrBodyTooLarge, err)
+	}
+}
+
+// Test a body which claims to be gzip but is not cannot be read.
+func TestDecompressionInvalidBody(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("Content-Encoding", "gzip")
+
+	if err := serveForError(req, WithDecompression()); !errors.Is(err, ErrBodyRead) {
+		t.Errorf("expected error %v got %v", ErrBodyRead, err)
+	}
+}
@@ -0,0 +1,181 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Header holding the unique ID of a webhook delivery.
+const webhookIDHeader = "X-Shopify-Webhook-Id"
+
+// Store of webhook IDs which were already delivered.
+// The context is the request's, so stores backed by a network service,
+// such as Redis to deduplicate across instances, can respect its cancellation.
+type DedupStore interface {
+	// MarkIfAbsent records the webhook ID as delivered, for the TTL, unless it
+	// already is and has not expired. Reports if it was marked by this call.
+	// Must be atomic, so concurrent deliveries across instances do not both pass.
+	MarkIfAbsent(ctx context.Context, id string, ttl time.Duration) (bool, error)
+
+	// Unmark removes the webhook ID, so its next delivery is handled again.
+	Unmark(ctx context.Context, id string) error
+}
+
+// Default time webhook IDs are kept for, matching how long Shopify retries a webhook.
+const DefaultDedupTTL = 48 * time.Hour
+
+// Skip webhooks which were already delivered, based on the `X-Shopify-Webhook-Id` header.
+// Duplicates are answered with a 200 so Shopify stops retrying, without running the next handler.
+// IDs are kept for the TTL, zero or less for `DefaultDedupTTL`.
+// Requests without a webhook ID are not deduplicated. If the store fails,
+// the failure is logged and the webhook is handled as if it was not seen.
+// The ID is marked before the next handler runs, so concurrent deliveries are
+// not both handled, and unmarked if the handler panics or responds with a 5xx,
+// so Shopify's retry is handled again. The status is only seen when written
+// through the given response writer, or one with a `Status() int` method, such
+// as Gin's and the Echo and Fiber wrappers'. With `WithAsync` and `WebhookVerifyRequest`
+// the handling happens elsewhere, so the ID stays marked and delivery is at most once.
+// Example: `WebhookVerify("abc123", handler, WithDeduplication(NewMemoryDedupStore(), time.Hour))`.
+func WithDeduplication(store DedupStore, ttl time.Duration) Option {
+	return func(cfg *config) {
+		if ttl <= 0 {
+			ttl = DefaultDedupTTL
+		}
+		cfg.dedup = store
+		cfg.dedupTTL = ttl
+	}
+}
+
+// Check if the webhook is a duplicate delivery, marking it as seen if not.
+// Once marked, the ID is kept in the returned request's context for `serveDeduped`.
+func isDuplicate(cfg *config, r *http.Request) (*http.Request, bool) {
+	id := r.Header.Get(webhookIDHeader)
+	if cfg.dedup == nil || id == "" {
+		// Not enabled or nothing to deduplicate on.
+		return r, false
+	}
+
+	ctx := r.Context()
+	marked, err := cfg.dedup.MarkIfAbsent(ctx, id, cfg.dedupTTL)
+	if err != nil {
+		if ctx.Err() == nil {
+			// Not from the client going away.
+			logDedupFailure(cfg, r, err)
+		}
+		return r, false
+	}
+	if !marked {
+		return r, true
+	}
+
+	return r.WithContext(context.WithValue(ctx, dedupKey, id)), false
+}
+
+// Run the next handler, unmarking the webhook ID it was marked with if it fails,
+// so the retry from Shopify is not answered as a duplicate and lost.
+func serveDeduped(cfg *config, fn http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	id, ok := r.Context().Value(dedupKey).(string)
+	if !ok {
+		serveNext(cfg, fn, w, r)
+		return
+	}
+
+	sw := &statusWriter{ResponseWriter: w}
+	done := false
+	defer func() {
+		if !done || responseStatus(sw) >= http.StatusInternalServerError {
+			// Panicked or failed, left for the retry.
+			unmark(cfg, r, id)
+		}
+	}()
+	serveNext(cfg, fn, sw, r)
+	done = true
+}
+
+// Unmark the webhook ID the request was marked with, if any, as it was not handled.
+func unmarkDelivery(cfg *config, r *http.Request) {
+	if id, ok := r.Context().Value(dedupKey).(string); ok {
+		unmark(cfg, r, id)
+	}
+}
+
+// Unmark the webhook ID in the store. Not cancelled with the request,
+// as the client may already be gone.
+func unmark(cfg *config, r *http.Request, id string) {
+	if err := cfg.dedup.Unmark(context.WithoutCancel(r.Context()), id); err != nil {
+		logDedupFailure(cfg, r, err)
+	}
+}
+
+// Get the status of the response, preferring one reported by the underlying writer.
+func responseStatus(sw *statusWriter) int {
+	if s, ok := sw.ResponseWriter.(interface{ Status() int }); ok {
+		return s.Status()
+	}
+	if sw.code == 0 {
+		// Nothing written yet, which is sent as a 200.
+		return http.StatusOK
+	}
+
+	return sw.code
+}
+
+// In-memory store of webhook IDs, each kept for its TTL.
+// Only deduplicates within a single process.
+type MemoryDedupStore struct {
+	mu    sync.Mutex
+	ids   map[string]time.Time
+	sweep int
+	now   func() time.Time
+}
+
+// Size the memory store grows to before expired IDs are first swept.
+const memoryDedupSweepSize = 1024
+
+// Create an in-memory store of webhook IDs.
+// Example: `NewMemoryDedupStore()`.
+func NewMemoryDedupStore() *MemoryDedupStore {
+	return &MemoryDedupStore{
+		ids:   make(map[string]time.Time),
+		sweep: memoryDedupSweepSize,
+		now:   time.Now,
+	}
+}
+
+// MarkIfAbsent records the webhook ID until the TTL passes, unless it is already
+// recorded and has not expired. Reports if it was recorded by this call.
+func (s *MemoryDedupStore) MarkIfAbsent(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if exp, ok := s.ids[id]; ok && now.Before(exp) {
+		return false, nil
+	}
+	s.ids[id] = now.Add(ttl)
+
+	if len(s.ids) >= s.sweep {
+		// Clear out expired IDs once the store doubled since the last sweep,
+		// so the cost is spread over the marks in between.
+		for k, exp := range s.ids {
+			if !now.Before(exp) {
+				delete(s.ids, k)
+			}
+		}
+		s.sweep = max(2*len(s.ids), memoryDedupSweepSize)
+	}
+
+	return true, nil
+}
+
+// Unmark removes the webhook ID.
+func (s *MemoryDedupStore) Unmark(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.ids, id)
+
+	return nil
+}
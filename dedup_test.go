@@ -0,0 +1,347 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// Builds a signed request with the webhook ID.
+func newDedupRequest(id string) *http.Request {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Webhook-Id", id)
+
+	return req
+}
+
+// Test duplicate deliveries are answered without running the next handler.
+func TestWithDeduplication(t *testing.T) {
+	var runs int
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+	})
+	h := WebhookVerify("secret", nh, WithDeduplication(NewMemoryDedupStore(), time.Hour))
+
+	// First delivery.
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newDedupRequest("b54557e4-bdd9-4b37-8a5f-bf7d70bcd043"))
+	if c := rec.Code; c != http.StatusOK || runs != 1 {
+		t.Errorf("expected first delivery to run with status code %v got %v", http.StatusOK, c)
+	}
+
+	// Duplicate delivery.
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newDedupRequest("b54557e4-bdd9-4b37-8a5f-bf7d70bcd043"))
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected duplicate delivery to have status code %v got %v", http.StatusOK, c)
+	}
+	if runs != 1 {
+		t.Errorf("expected next handler to run once got %v", runs)
+	}
+
+	// Another webhook.
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("a7e7c3c4-5f2a-4d56-9f0e-3e1b2c7f1a11"))
+	if runs != 2 {
+		t.Errorf("expected next handler to run for a new webhook ID got %v runs", runs)
+	}
+}
+
+// Test concurrent deliveries of the same webhook only run once.
+func TestWithDeduplicationConcurrent(t *testing.T) {
+	var runs int32
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&runs, 1)
+	})
+	h := WebhookVerify("secret", nh, WithDeduplication(NewMemoryDedupStore(), time.Hour))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4-bdd9-4b37-8a5f-bf7d70bcd043"))
+		}()
+	}
+	wg.Wait()
+
+	if runs != 1 {
+		t.Errorf("expected next handler to run once got %v", runs)
+	}
+}
+
+// Test a delivery whose handler fails is unmarked, so the retry is handled.
+func TestDeduplicationHandlerFailure(t *testing.T) {
+	var runs int
+	fail := true
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+		if fail {
+			http.Error(w, "Database unavailable", http.StatusServiceUnavailable)
+		}
+	})
+	h := WebhookVerify("secret", nh, WithDeduplication(NewMemoryDedupStore(), time.Hour))
+
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+	fail = false
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+	if runs != 2 {
+		t.Errorf("expected the retry of a failed delivery to run got %v runs", runs)
+	}
+
+	// Handled now, so the next delivery is a duplicate.
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+	if runs != 2 {
+		t.Errorf("expected a duplicate of a handled delivery to not run got %v runs", runs)
+	}
+}
+
+// Test a delivery whose handler panics is unmarked, with or without recovery.
+func TestDeduplicationHandlerPanic(t *testing.T) {
+	opts := map[string][]Option{
+		"recovered":   {WithRecover(func(w http.ResponseWriter, r *http.Request, recovered any) {})},
+		"unrecovered": nil,
+	}
+	for name, extra := range opts {
+		var runs int
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			runs++
+			if runs == 1 {
+				panic("boom")
+			}
+		})
+		h := WebhookVerify("secret", nh, append(extra, WithDeduplication(NewMemoryDedupStore(), time.Hour))...)
+
+		func() {
+			defer func() {
+				recover()
+			}()
+			h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+		}()
+		h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+		if runs != 2 {
+			t.Errorf("expected the retry of a %v panic to run got %v runs", name, runs)
+		}
+	}
+}
+
+// Test IDs expire from the memory store after the TTL.
+func TestMemoryDedupStoreExpiry(t *testing.T) {
+	now := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryDedupStore()
+	s.now = func() time.Time {
+		return now
+	}
+
+	ctx := context.Background()
+	if marked, _ := s.MarkIfAbsent(ctx, "abc", time.Minute); !marked {
+		t.Errorf("expected ID to be marked")
+	}
+	if marked, _ := s.MarkIfAbsent(ctx, "abc", time.Minute); marked {
+		t.Errorf("expected ID to already be marked")
+	}
+
+	now = now.Add(2 * time.Minute)
+	if marked, _ := s.MarkIfAbsent(ctx, "abc", time.Minute); !marked {
+		t.Errorf("expected ID to have expired")
+	}
+}
+
+// Test expired IDs are swept from the memory store as it grows.
+func TestMemoryDedupStoreSweep(t *testing.T) {
+	now := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+	s := NewMemoryDedupStore()
+	s.now = func() time.Time {
+		return now
+	}
+
+	ctx := context.Background()
+	for i := 0; i < memoryDedupSweepSize-1; i++ {
+		s.MarkIfAbsent(ctx, fmt.Sprintf("old-%d", i), time.Minute)
+	}
+
+	now = now.Add(2 * time.Minute)
+	s.MarkIfAbsent(ctx, "new", time.Minute)
+	if l := len(s.ids); l != 1 {
+		t.Errorf("expected expired IDs to be swept got %v IDs", l)
+	}
+}
+
+// Store shared between instances, such as one backed by Redis.
+type sharedDedupStore struct {
+	mu   sync.Mutex
+	ids  map[string]time.Duration
+	err  error
+	ctxs []context.Context
+}
+
+func (s *sharedDedupStore) MarkIfAbsent(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ctxs = append(s.ctxs, ctx)
+
+	if s.err != nil {
+		return false, s.err
+	}
+	if _, ok := s.ids[id]; ok {
+		return false, nil
+	}
+	s.ids[id] = ttl
+
+	return true, nil
+}
+
+func (s *sharedDedupStore) Unmark(ctx context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.ids, id)
+
+	return s.err
+}
+
+// Test instances sharing a store deduplicate each other's deliveries.
+func TestDeduplicationShared(t *testing.T) {
+	store := &sharedDedupStore{ids: make(map[string]time.Duration)}
+
+	var runs int
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		runs++
+	})
+	a := WebhookVerify("secret", nh, WithDeduplication(store, time.Hour))
+	b := WebhookVerify("secret", nh, WithDeduplication(store, time.Hour))
+
+	a.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+	b.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+	if runs != 1 {
+		t.Errorf("expected the webhook to run once across instances got %v", runs)
+	}
+
+	if ttl := store.ids["b54557e4"]; ttl != time.Hour {
+		t.Errorf("expected the ID to be marked for %v got %v", time.Hour, ttl)
+	}
+
+	// The request context is passed to the store.
+	type ctxKey struct{}
+	req := newDedupRequest("a7e7c3c4")
+	req = req.WithContext(context.WithValue(req.Context(), ctxKey{}, "request"))
+	a.ServeHTTP(httptest.NewRecorder(), req)
+	if last := store.ctxs[len(store.ctxs)-1]; last.Value(ctxKey{}) != "request" {
+		t.Errorf("expected the request context to be passed to the store")
+	}
+}
+
+// Test the default TTL is used when none is given.
+func TestDeduplicationDefaultTTL(t *testing.T) {
+	cfg := newConfig([]Option{WithDeduplication(NewMemoryDedupStore(), 0)})
+	if ttl := cfg.dedupTTL; ttl != DefaultDedupTTL {
+		t.Errorf("expected TTL of %v got %v", DefaultDedupTTL, ttl)
+	}
+}
+
+// Test a failing store is logged and the webhook is still handled.
+func TestDeduplicationStoreError(t *testing.T) {
+	ch := &captureHandler{}
+	store := &sharedDedupStore{ids: make(map[string]time.Duration), err: errors.New("connection refused")}
+
+	var runs int
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		runs++
+	}, WithDeduplication(store, time.Hour), WithLogger(slog.New(ch)))
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+
+	if runs != 1 {
+		t.Errorf("expected the webhook to be handled got %v runs", runs)
+	}
+
+	if len(ch.records) != 1 || recordAttrs(ch.records[0])["reason"] != "connection refused" {
+		t.Errorf("expected the store failure to be logged got %v records", len(ch.records))
+	}
+}
+
+// Store whose lookups block until the context is done.
+type slowDedupStore struct {
+	started chan struct{}
+}
+
+func (s *slowDedupStore) MarkIfAbsent(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	close(s.started)
+	<-ctx.Done()
+
+	return false, ctx.Err()
+}
+
+func (s *slowDedupStore) Unmark(ctx context.Context, id string) error {
+	return nil
+}
+
+// Store which marks the webhook ID, then has the client go away.
+type cancellingDedupStore struct {
+	*MemoryDedupStore
+	cancel context.CancelFunc
+}
+
+func (s *cancellingDedupStore) MarkIfAbsent(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	marked, err := s.MemoryDedupStore.MarkIfAbsent(ctx, id, ttl)
+	s.cancel()
+
+	return marked, err
+}
+
+// Test a request cancelled once marked is unmarked, so the retry is handled.
+func TestDeduplicationCancelledAfterMark(t *testing.T) {
+	store := &cancellingDedupStore{MemoryDedupStore: NewMemoryDedupStore()}
+
+	var runs int
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		runs++
+	}, WithDeduplication(store, time.Hour))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	store.cancel = cancel
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4").WithContext(ctx))
+	if runs != 0 {
+		t.Errorf("expected next handler to not run for a cancelled request got %v runs", runs)
+	}
+
+	// The retry from Shopify.
+	store.cancel = func() {}
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("b54557e4"))
+	if runs != 1 {
+		t.Errorf("expected the retry to run got %v runs", runs)
+	}
+}
+
+// Test a request cancelled during a slow dedup lookup does not run the handler.
+func TestDeduplicationCancelled(t *testing.T) {
+	store := &slowDedupStore{started: make(chan struct{})}
+	ctx, cancel := context.WithCancel(context.Background())
+	req := newDedupRequest("b54557e4").WithContext(ctx)
+
+	// Client goes away once the lookup is underway.
+	go func() {
+		<-store.started
+		cancel()
+	}()
+
+	var logs bytes.Buffer
+	ran := false
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithDeduplication(store, time.Hour), WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))).ServeHTTP(httptest.NewRecorder(), req)
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+
+	if logs.Len() != 0 {
+		t.Errorf("expected no dedup failure to be logged got %q", logs.String())
+	}
+}
@@ -0,0 +1,60 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Routes verified webhooks to handlers by their topic.
+// Register the handlers before serving requests.
+type Dispatcher struct {
+	handlers map[string]http.Handler
+	fallback http.Handler
+	verify   http.HandlerFunc
+}
+
+// Create a dispatcher which verifies webhooks with the secret key before routing them.
+// Example: `d := NewDispatcher("abc123"); d.Handle("orders/create", ordersHandler)`.
+func NewDispatcher(key string, opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		handlers: make(map[string]http.Handler),
+	}
+	d.verify = WebhookVerify(key, d.route, opts...)
+
+	return d
+}
+
+// Handle webhooks for the topic, such as `orders/create`, with the handler.
+// With options passing unverified webhooks through, the handler must check `IsVerified`.
+func (d *Dispatcher) Handle(topic string, h http.Handler) {
+	d.handlers[topic] = h
+}
+
+// Handle webhooks for topics without a handler.
+// Without a default handler, a 404 is returned for them.
+func (d *Dispatcher) Default(h http.Handler) {
+	d.fallback = h
+}
+
+// Verify the webhook and route it to the handler for its topic.
+func (d *Dispatcher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	d.verify(w, r)
+}
+
+// Route the webhook by its topic.
+// Webhooks passed through unverified, such as with `WithAllowInsecure`, are routed
+// on the topic they claim.
+func (d *Dispatcher) route(w http.ResponseWriter, r *http.Request) {
+	topic, ok := TopicFromContext(r.Context())
+	if !ok && !IsVerified(r.Context()) {
+		topic = r.Header.Get("X-Shopify-Topic")
+	}
+	if h, ok := d.handlers[topic]; ok {
+		h.ServeHTTP(w, r)
+		return
+	}
+
+	if d.fallback != nil {
+		d.fallback.ServeHTTP(w, r)
+		return
+	}
+
+	http.NotFound(w, r)
+}
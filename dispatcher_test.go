@@ -0,0 +1,104 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Builds a request for the topic, signed with the key.
+func newTopicRequest(key string, topic string) *http.Request {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign(key, body))
+	req.Header.Set("X-Shopify-Topic", topic)
+
+	return req
+}
+
+// Handler which records the name it was registered as.
+func recordHandler(name string, handled *[]string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*handled = append(*handled, name)
+	})
+}
+
+// Test verified webhooks reach the handler for their topic.
+func TestDispatcher(t *testing.T) {
+	var handled []string
+	d := NewDispatcher("secret")
+	d.Handle("orders/create", recordHandler("orders", &handled))
+	d.Handle("app/uninstalled", recordHandler("uninstalled", &handled))
+
+	d.ServeHTTP(httptest.NewRecorder(), newTopicRequest("secret", "app/uninstalled"))
+	d.ServeHTTP(httptest.NewRecorder(), newTopicRequest("secret", "orders/create"))
+
+	if len(handled) != 2 || handled[0] != "uninstalled" || handled[1] != "orders" {
+		t.Errorf("expected webhooks to be routed by topic got %v", handled)
+	}
+}
+
+// Test unverified webhooks never reach a handler.
+func TestDispatcherUnverified(t *testing.T) {
+	var handled []string
+	d := NewDispatcher("secret")
+	d.Handle("orders/create", recordHandler("orders", &handled))
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, newTopicRequest("other", "orders/create"))
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if len(handled) != 0 {
+		t.Errorf("expected no handler to run got %v", handled)
+	}
+}
+
+// Test webhooks passed through unverified are routed on their claimed topic.
+func TestDispatcherPassThrough(t *testing.T) {
+	opts := map[string]Option{
+		"insecure": WithAllowInsecure(),
+		"enforce":  WithEnforceTopics("shop/redact"),
+		"skip":     WithSkipVerification(func(r *http.Request) bool { return true }),
+	}
+
+	for name, opt := range opts {
+		verified := true
+		d := NewDispatcher("secret", opt)
+		d.Handle("orders/create", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			verified = IsVerified(r.Context())
+		}))
+
+		rec := httptest.NewRecorder()
+		d.ServeHTTP(rec, newTopicRequest("other", "orders/create"))
+
+		if c := rec.Code; c != http.StatusOK {
+			t.Errorf("%s: expected status code %v got %v", name, http.StatusOK, c)
+		}
+
+		if verified {
+			t.Errorf("%s: expected the handler to see an unverified webhook but did not", name)
+		}
+	}
+}
+
+// Test unregistered topics hit the default handler, or a 404 without one.
+func TestDispatcherUnregistered(t *testing.T) {
+	var handled []string
+	d := NewDispatcher("secret")
+	d.Handle("orders/create", recordHandler("orders", &handled))
+
+	rec := httptest.NewRecorder()
+	d.ServeHTTP(rec, newTopicRequest("secret", "products/update"))
+	if c := rec.Code; c != http.StatusNotFound {
+		t.Errorf("expected status code %v got %v", http.StatusNotFound, c)
+	}
+
+	d.Default(recordHandler("default", &handled))
+	d.ServeHTTP(httptest.NewRecorder(), newTopicRequest("secret", "products/update"))
+	if len(handled) != 1 || handled[0] != "default" {
+		t.Errorf("expected default handler to run got %v", handled)
+	}
+}
@@ -0,0 +1,93 @@
+package http_shopify_webhook
+
+import (
+	"errors"
+	"net/http"
+)
+
+// Reasons for a webhook to fail verification.
+// Passed to the error handler, compare with `errors.Is`.
+var (
+	ErrMethodNotAllowed     = errors.New("method not allowed")
+	ErrUnsupportedMediaType = errors.New("unsupported media type")
+	ErrMissingShop          = errors.New("missing shop domain header")
+	ErrMissingHMAC          = errors.New("missing HMAC header")
+	ErrAmbiguousHMAC        = errors.New("ambiguous HMAC headers")
+	ErrMissingHeader        = errors.New("missing webhook header")
+	ErrHeaderTooLong        = errors.New("webhook header too long")
+	ErrInvalidShopDomain    = errors.New("invalid shop domain")
+	ErrInvalidSignature     = errors.New("invalid webhook signature")
+	ErrUnknownShop          = errors.New("unknown shop")
+	ErrShopNotAllowed       = errors.New("shop not allowed")
+	ErrRateLimited          = errors.New("webhook rate limit exceeded")
+	ErrBodyTooLarge         = errors.New("webhook body too large")
+	ErrBodyRead             = errors.New("unable to read webhook body")
+	ErrEmptyBody            = errors.New("empty webhook body")
+	ErrReadTimeout          = errors.New("timed out reading webhook body")
+	ErrMissingTimestamp     = errors.New("missing webhook timestamp header")
+	ErrInvalidTimestamp     = errors.New("invalid webhook timestamp")
+	ErrExpired              = errors.New("webhook expired")
+)
+
+// Default handling for verification failures.
+// Writes a plain text error with a status matching the reason.
+func defaultErrorHandler(cfg *config) func(w http.ResponseWriter, r *http.Request, err error) {
+	return func(w http.ResponseWriter, r *http.Request, err error) {
+		writeError(cfg, w, err)
+	}
+}
+
+// Write the error for the reason, as plain text or the custom response body.
+func writeError(cfg *config, w http.ResponseWriter, err error) {
+	msg, code := errorResponse(cfg, err)
+	if cfg.responseBody != nil {
+		w.Header().Set("Content-Type", cfg.responseType)
+		w.Header().Set("X-Content-Type-Options", "nosniff")
+		w.WriteHeader(code)
+		w.Write(cfg.responseBody)
+		return
+	}
+
+	http.Error(w, msg, code)
+}
+
+// Get the plain text message and the status for the reason.
+func errorResponse(cfg *config, err error) (string, int) {
+	var mhe *MissingHeaderError
+	switch {
+	case errors.Is(err, ErrMethodNotAllowed):
+		return "Method not allowed", http.StatusMethodNotAllowed
+	case errors.Is(err, ErrUnsupportedMediaType):
+		return "Unsupported media type", http.StatusUnsupportedMediaType
+	case errors.As(err, &mhe):
+		return "Missing webhook header " + mhe.Header, http.StatusBadRequest
+	case errors.Is(err, ErrHeaderTooLong):
+		return "Webhook header too long", http.StatusBadRequest
+	case errors.Is(err, ErrAmbiguousHMAC):
+		return "Ambiguous webhook HMAC", http.StatusBadRequest
+	case errors.Is(err, ErrInvalidShopDomain):
+		return "Invalid shop domain", http.StatusBadRequest
+	case errors.Is(err, ErrUnknownShop):
+		return "Unknown webhook shop", http.StatusUnauthorized
+	case errors.Is(err, ErrShopNotAllowed):
+		return "Webhook shop not allowed", http.StatusForbidden
+	case errors.Is(err, ErrRateLimited):
+		return "Too many webhooks", http.StatusTooManyRequests
+	case errors.Is(err, ErrBodyTooLarge):
+		return "Webhook body too large", http.StatusRequestEntityTooLarge
+	case errors.Is(err, ErrReadTimeout):
+		return "Timed out reading webhook body", http.StatusRequestTimeout
+	case errors.Is(err, ErrEmptyBody):
+		return "Empty webhook body", http.StatusBadRequest
+	case errors.Is(err, ErrBodyRead):
+		return "Unable to read webhook body", http.StatusBadRequest
+	case errors.Is(err, ErrMissingTimestamp), errors.Is(err, ErrInvalidTimestamp):
+		return "Invalid webhook timestamp", http.StatusBadRequest
+	case errors.Is(err, ErrExpired):
+		return "Webhook expired", http.StatusBadRequest
+	case errors.Is(err, ErrInvalidSignature):
+		return "Invalid webhook signature", cfg.signatureStatus
+	default:
+		return "Invalid webhook signature", http.StatusBadRequest
+	}
+}
@@ -0,0 +1,181 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Serves the request and returns the error passed to the error handler.
+func serveForError(req *http.Request, opts ...Option) error {
+	var herr error
+	eh := func(w http.ResponseWriter, r *http.Request, err error) {
+		herr = err
+	}
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	opts = append(opts, WithErrorHandler(eh))
+	WebhookVerify("secret", nh, opts...).ServeHTTP(httptest.NewRecorder(), req)
+
+	return herr
+}
+
+// Builds a request with the body and headers.
+func newWebhookRequest(body io.Reader, shop string, hmac string) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", body)
+	req.Header.Set("X-Shopify-Shop-Domain", shop)
+	req.Header.Set("X-Shopify-Hmac-Sha256", hmac)
+
+	return req
+}
+
+// Test each failure produces the matching sentinel error.
+func TestSentinelErrors(t *testing.T) {
+	body := `{"key":"value"}`
+	shop := "example.myshopify.com"
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		opts []Option
+		err  error
+	}{
+		{
+			name: "missing shop",
+			req:  newWebhookRequest(bytes.NewBufferString(body), "", sign("secret", body)),
+			err:  ErrMissingShop,
+		},
+		{
+			name: "missing hmac",
+			req:  newWebhookRequest(bytes.NewBufferString(body), shop, ""),
+			err:  ErrMissingHMAC,
+		},
+		{
+			name: "invalid signature",
+			req:  newWebhookRequest(bytes.NewBufferString(body), shop, sign("other", body)),
+			err:  ErrInvalidSignature,
+		},
+		{
+			name: "body read",
+			req:  newWebhookRequest(&errReader{}, shop, sign("secret", body)),
+			err:  ErrBodyRead,
+		},
+		{
+			name: "body too large",
+			req:  newWebhookRequest(bytes.NewBufferString(body), shop, sign("secret", body)),
+			opts: []Option{WithMaxBodySize(1)},
+			err:  ErrBodyTooLarge,
+		},
+	}
+
+	for _, tt := range tests {
+		if err := serveForError(tt.req, tt.opts...); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// Test an unknown shop produces the matching sentinel error.
+func TestSentinelErrorUnknownShop(t *testing.T) {
+	var herr error
+	eh := func(w http.ResponseWriter, r *http.Request, err error) {
+		herr = err
+	}
+	lookup := func(shop string) (string, bool) {
+		return "", false
+	}
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})
+
+	req := newWebhookRequest(bytes.NewBufferString(`{"key":"value"}`), "example.myshopify.com", sign("secret", `{"key":"value"}`))
+	WebhookVerifyFunc(lookup, nh, WithErrorHandler(eh)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !errors.Is(herr, ErrUnknownShop) {
+		t.Errorf("expected error %v got %v", ErrUnknownShop, herr)
+	}
+}
+
+// Reader which records if, and how much, it was read from.
+type trackingReader struct {
+	io.Reader
+	read bool
+	n    int
+}
+
+func (tr *trackingReader) Read(p []byte) (int, error) {
+	tr.read = true
+	n, err := tr.Reader.Read(p)
+	tr.n += n
+	return n, err
+}
+
+// Test a missing or empty HMAC header is rejected before reading the body.
+func TestMissingHMACSkipsBody(t *testing.T) {
+	for _, set := range []bool{false, true} {
+		tr := &trackingReader{Reader: bytes.NewBufferString(`{"key":"value"}`)}
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", tr)
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		if set {
+			req.Header.Set("X-Shopify-Hmac-Sha256", "")
+		}
+
+		if err := serveForError(req); !errors.Is(err, ErrMissingHMAC) {
+			t.Errorf("expected error %v got %v", ErrMissingHMAC, err)
+		}
+
+		if tr.read {
+			t.Errorf("expected body to not be read but it was")
+		}
+	}
+}
+
+// Test a missing shop header is rejected before reading the body.
+func TestMissingShopSkipsBody(t *testing.T) {
+	tr := &trackingReader{Reader: bytes.NewBufferString(`{"key":"value"}`)}
+	req := newWebhookRequest(tr, "", sign("secret", `{"key":"value"}`))
+
+	if err := serveForError(req); !errors.Is(err, ErrMissingShop) {
+		t.Errorf("expected error %v got %v", ErrMissingShop, err)
+	}
+
+	if tr.read {
+		t.Errorf("expected body to not be read but it was")
+	}
+}
+
+// Test requests rejected on their headers read zero bytes of a large body.
+func TestHeaderRejectedReadsNoBody(t *testing.T) {
+	body := strings.Repeat("x", 1<<20)
+	hmac := sign("secret", body)
+	tests := []struct {
+		name  string
+		setup func(r *http.Request)
+		opts  []Option
+	}{
+		{name: "method", setup: func(r *http.Request) { r.Method = http.MethodGet }},
+		{name: "content type", setup: func(r *http.Request) { r.Header.Set("Content-Type", "text/plain") }, opts: []Option{WithContentType("application/json")}},
+		{name: "missing shop", setup: func(r *http.Request) { r.Header.Del("X-Shopify-Shop-Domain") }},
+		{name: "missing HMAC", setup: func(r *http.Request) { r.Header.Del("X-Shopify-Hmac-Sha256") }},
+		{name: "invalid shop domain", setup: func(r *http.Request) { r.Header.Set("X-Shopify-Shop-Domain", "example.com") }, opts: []Option{WithShopDomainValidation()}},
+		{name: "header too long", setup: func(r *http.Request) { r.Header.Set("X-Shopify-Topic", strings.Repeat("a", 300)) }, opts: []Option{WithMaxHeaderValueLength(256)}},
+		{name: "missing timestamp", setup: func(r *http.Request) {}, opts: []Option{WithMaxAge(time.Minute)}},
+	}
+
+	for _, tt := range tests {
+		tr := &trackingReader{Reader: strings.NewReader(body)}
+		req := newWebhookRequest(tr, "example.myshopify.com", hmac)
+		tt.setup(req)
+
+		if err := serveForError(req, tt.opts...); err == nil {
+			t.Errorf("%s: expected the request to be rejected", tt.name)
+		}
+
+		if tr.n != 0 {
+			t.Errorf("%s: expected zero bytes of the body to be read got %v", tt.name, tr.n)
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package http_shopify_webhook_test
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	hsw "github.com/ohmybrew/http_shopify_webhook"
+)
+
+// Sign a request for a test and pass it through the verifier.
+func ExampleComputeHMAC() {
+	body := []byte(`{"id":1}`)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBuffer(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", hsw.ComputeHMAC("secret", body))
+
+	rec := httptest.NewRecorder()
+	hsw.WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprintf(w, "Ok")
+	}).ServeHTTP(rec, req)
+
+	fmt.Println(rec.Code, rec.Body.String())
+	// Output: 200 Ok
+}
+
+// Check a saved payload against its HMAC header, such as piped to stdin.
+func ExampleVerifyStream() {
+	body := []byte(`{"id":1}`)
+
+	ok, err := hsw.VerifyStream("secret", hsw.ComputeHMAC("secret", body), bytes.NewReader(body))
+	fmt.Println(ok, err)
+	// Output: true <nil>
+}
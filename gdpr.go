@@ -0,0 +1,41 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Topics of the mandatory GDPR webhooks.
+const (
+	TopicCustomersDataRequest = "customers/data_request"
+	TopicCustomersRedact      = "customers/redact"
+	TopicShopRedact           = "shop/redact"
+)
+
+// Handlers for the mandatory GDPR webhooks every public app must handle.
+type GDPRHandlers struct {
+	// Handles `customers/data_request`, a customer asking for their data.
+	CustomersDataRequest http.HandlerFunc
+
+	// Handles `customers/redact`, a request to erase a customer's data.
+	CustomersRedact http.HandlerFunc
+
+	// Handles `shop/redact`, a request to erase a shop's data after uninstalling.
+	ShopRedact http.HandlerFunc
+}
+
+// Create a handler for the mandatory GDPR webhooks, verified with the secret key.
+// Routes each topic to its handler, other topics and ones without a handler get a 404.
+// With options passing unverified webhooks through, the handlers must check `IsVerified`.
+// Example: `http.Handle("/webhook/gdpr", GDPRHandler("abc123", GDPRHandlers{...}))`.
+func GDPRHandler(key string, handlers GDPRHandlers, opts ...Option) http.Handler {
+	d := NewDispatcher(key, opts...)
+	for topic, h := range map[string]http.HandlerFunc{
+		TopicCustomersDataRequest: handlers.CustomersDataRequest,
+		TopicCustomersRedact:      handlers.CustomersRedact,
+		TopicShopRedact:           handlers.ShopRedact,
+	} {
+		if h != nil {
+			d.Handle(topic, h)
+		}
+	}
+
+	return d
+}
@@ -0,0 +1,87 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Builds the GDPR handler, recording the handled topics.
+func newGDPRHandler(handled *[]string) http.Handler {
+	return GDPRHandler("secret", GDPRHandlers{
+		CustomersDataRequest: recordHandler("data_request", handled).ServeHTTP,
+		CustomersRedact:      recordHandler("customers_redact", handled).ServeHTTP,
+		ShopRedact:           recordHandler("shop_redact", handled).ServeHTTP,
+	})
+}
+
+// Test each GDPR topic reaches its handler.
+func TestGDPRHandler(t *testing.T) {
+	tests := []struct {
+		topic string
+		name  string
+	}{
+		{topic: TopicCustomersDataRequest, name: "data_request"},
+		{topic: TopicCustomersRedact, name: "customers_redact"},
+		{topic: TopicShopRedact, name: "shop_redact"},
+	}
+
+	for _, tt := range tests {
+		var handled []string
+		rec := httptest.NewRecorder()
+		newGDPRHandler(&handled).ServeHTTP(rec, newTopicRequest("secret", tt.topic))
+
+		if c := rec.Code; c != http.StatusOK {
+			t.Errorf("expected status code %v for %s got %v", http.StatusOK, tt.topic, c)
+		}
+
+		if len(handled) != 1 || handled[0] != tt.name {
+			t.Errorf("expected %s to be handled by %s got %v", tt.topic, tt.name, handled)
+		}
+	}
+}
+
+// Test other topics are not handled.
+func TestGDPRHandlerUnknownTopic(t *testing.T) {
+	var handled []string
+	rec := httptest.NewRecorder()
+	newGDPRHandler(&handled).ServeHTTP(rec, newTopicRequest("secret", "orders/create"))
+
+	if c := rec.Code; c != http.StatusNotFound {
+		t.Errorf("expected status code %v got %v", http.StatusNotFound, c)
+	}
+
+	if len(handled) != 0 {
+		t.Errorf("expected no handlers to run got %v", handled)
+	}
+}
+
+// Test GDPR webhooks are verified before being handled.
+func TestGDPRHandlerInvalid(t *testing.T) {
+	var handled []string
+	rec := httptest.NewRecorder()
+	newGDPRHandler(&handled).ServeHTTP(rec, newTopicRequest("other", TopicShopRedact))
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if len(handled) != 0 {
+		t.Errorf("expected no handlers to run got %v", handled)
+	}
+}
+
+// Test topics without a handler are not found.
+func TestGDPRHandlerMissingHandler(t *testing.T) {
+	var handled []string
+	h := GDPRHandler("secret", GDPRHandlers{
+		ShopRedact: recordHandler("shop_redact", &handled).ServeHTTP,
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newTopicRequest("secret", TopicCustomersRedact))
+
+	if c := rec.Code; c != http.StatusNotFound {
+		t.Errorf("expected status code %v got %v", http.StatusNotFound, c)
+	}
+}
@@ -0,0 +1,66 @@
+package http_shopify_webhook
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Reject verified webhooks missing any of the headers with a 400 naming the header,
+// such as `X-Shopify-Topic` or `X-Shopify-Webhook-Id` for handlers depending on them.
+// Example: `WebhookVerify("abc123", handler, WithRequiredHeaders("X-Shopify-Topic"))`.
+func WithRequiredHeaders(names ...string) Option {
+	return func(cfg *config) {
+		cfg.requiredHeaders = names
+	}
+}
+
+// Missing required header, matches `ErrMissingHeader` with `errors.Is`.
+type MissingHeaderError struct {
+	Header string
+}
+
+func (e *MissingHeaderError) Error() string {
+	return ErrMissingHeader.Error() + " " + e.Header
+}
+
+func (e *MissingHeaderError) Unwrap() error {
+	return ErrMissingHeader
+}
+
+// Check all the required headers are present.
+func checkRequiredHeaders(cfg *config, r *http.Request) error {
+	for _, name := range cfg.requiredHeaders {
+		if strings.TrimSpace(r.Header.Get(name)) == "" {
+			return &MissingHeaderError{Header: http.CanonicalHeaderKey(name)}
+		}
+	}
+
+	return nil
+}
+
+// Reject webhooks with any of the Shopify headers, such as the shop or HMAC, longer than n
+// with a 400, before any work is done on them. Not limited by default.
+// Example: `WebhookVerify("abc123", handler, WithMaxHeaderValueLength(256))`.
+func WithMaxHeaderValueLength(n int) Option {
+	return func(cfg *config) {
+		cfg.maxHeaderLength = n
+	}
+}
+
+// Check the Shopify headers are within the max length, if one is set.
+func checkHeaderLengths(cfg *config, r *http.Request) error {
+	if cfg.maxHeaderLength <= 0 {
+		return nil
+	}
+
+	for _, name := range []string{cfg.hmacHeader, cfg.shopHeader, cfg.timestampHeader, webhookIDHeader, "X-Shopify-Topic"} {
+		for _, v := range r.Header.Values(name) {
+			if len(v) > cfg.maxHeaderLength {
+				return fmt.Errorf("%w: %s", ErrHeaderTooLong, http.CanonicalHeaderKey(name))
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,110 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Test a verified request missing a required header is rejected, naming it.
+func TestWithRequiredHeaders(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithRequiredHeaders("X-Shopify-Topic", "x-shopify-webhook-id")).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if b := rec.Body.String(); !strings.Contains(b, "X-Shopify-Webhook-Id") {
+		t.Errorf("expected response to name the missing header got %q", b)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+}
+
+// Test the missing header error matches the sentinel and names the header.
+func TestRequiredHeadersError(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	err := serveForError(req, WithRequiredHeaders("X-Shopify-Topic"))
+	if !errors.Is(err, ErrMissingHeader) {
+		t.Errorf("expected error %v got %v", ErrMissingHeader, err)
+	}
+
+	var mhe *MissingHeaderError
+	if !errors.As(err, &mhe) || mhe.Header != "X-Shopify-Topic" {
+		t.Errorf("expected error naming %q got %v", "X-Shopify-Topic", err)
+	}
+}
+
+// Test a request with all required headers is verified.
+func TestRequiredHeadersPresent(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+	req.Header.Set("X-Shopify-Webhook-Id", "b54557e4")
+
+	if err := serveForError(req, WithRequiredHeaders("X-Shopify-Topic", "X-Shopify-Webhook-Id")); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
+
+// Test webhooks with headers within the max length are verified.
+func TestWithMaxHeaderValueLength(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+
+	if err := serveForError(req, WithMaxHeaderValueLength(256)); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
+
+// Test webhooks with an overly long header are rejected before the body is read.
+func TestWithMaxHeaderValueLengthTooLong(t *testing.T) {
+	body := `{"key":"value"}`
+	for _, name := range []string{"X-Shopify-Shop-Domain", "X-Shopify-Hmac-Sha256", "X-Shopify-Topic"} {
+		tr := &trackingReader{Reader: bytes.NewBufferString(body)}
+		req := newWebhookRequest(tr, "example.myshopify.com", sign("secret", body))
+		req.Header.Set(name, strings.Repeat("a", 64*1024))
+
+		rec := httptest.NewRecorder()
+		WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMaxHeaderValueLength(256)).ServeHTTP(rec, req)
+
+		if c := rec.Code; c != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %v got %v", name, http.StatusBadRequest, c)
+		}
+
+		if b := rec.Body.String(); b != "Webhook header too long\n" {
+			t.Errorf("%s: expected body %q got %q", name, "Webhook header too long\n", b)
+		}
+
+		if tr.read {
+			t.Errorf("%s: expected body to not be read", name)
+		}
+	}
+}
+
+// Test the header too long error matches the sentinel.
+func TestWithMaxHeaderValueLengthError(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Webhook-Id", strings.Repeat("a", 300))
+
+	if err := serveForError(req, WithMaxHeaderValueLength(256)); !errors.Is(err, ErrHeaderTooLong) {
+		t.Errorf("expected error %v got %v", ErrHeaderTooLong, err)
+	}
+}
@@ -0,0 +1,70 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Try each of the differing values of the HMAC header, sent more than once,
+// such as by a misconfigured proxy, passing if any of them matches.
+// By default, differing values are rejected with a 400 as `ErrAmbiguousHMAC`,
+// since only one of them can be the one Shopify set. Identical values always
+// count as one.
+// Example: `WebhookVerify("abc123", handler, WithTryAllHMACHeaders())`.
+func WithTryAllHMACHeaders() Option {
+	return func(cfg *config) {
+		cfg.tryAllHMACs = true
+	}
+}
+
+// Get the distinct, non-empty values of the HMAC header.
+// More than one is ambiguous, unless all are to be tried.
+func hmacHeaders(cfg *config, r *http.Request) ([]string, error) {
+	var shmacs []string
+	for _, v := range r.Header.Values(cfg.hmacHeader) {
+		v = strings.TrimSpace(v)
+		if v == "" || containsString(shmacs, v) {
+			continue
+		}
+		shmacs = append(shmacs, v)
+	}
+	if len(shmacs) > 1 && !cfg.tryAllHMACs {
+		return nil, ErrAmbiguousHMAC
+	}
+
+	return shmacs, nil
+}
+
+// Check if the value is in the list.
+func containsString(list []string, v string) bool {
+	for _, s := range list {
+		if s == v {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Verify the body against each of the HMACs and keys, passing if any matches.
+// All are tried, so the time taken does not reveal which one matched.
+func verifyRequestHMACs(cfg *config, keys []string, shop string, shmacs []string, bb []byte) error {
+	return verifyAny(shmacs, func(shmac string) error {
+		return verifyRequestKeys(cfg.hashFunc, cfg.decodeHMAC, keys, shop, shmac, bb)
+	})
+}
+
+// Run the verification for each of the HMACs, passing if any of them does.
+// Otherwise, returns the last reason for the failure.
+func verifyAny(shmacs []string, verify func(shmac string) error) error {
+	err := ErrInvalidSignature
+	for _, shmac := range shmacs {
+		if herr := verify(shmac); herr == nil {
+			err = nil
+		} else if err != nil {
+			err = herr
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,77 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs a request with the HMAC header values through the verifier with the options.
+func serveHMACHeaders(values []string, opts ...Option) *httptest.ResponseRecorder {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", "")
+	req.Header.Del("X-Shopify-Hmac-Sha256")
+	for _, v := range values {
+		req.Header.Add("X-Shopify-Hmac-Sha256", v)
+	}
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, opts...).ServeHTTP(rec, req)
+
+	return rec
+}
+
+// Test single, identical, and differing HMAC headers, with and without trying them all.
+func TestHMACHeaders(t *testing.T) {
+	body := `{"key":"value"}`
+	good := sign("secret", body)
+	bad := sign("other", body)
+
+	tests := []struct {
+		name   string
+		values []string
+		opts   []Option
+		code   int
+		msg    string
+	}{
+		{name: "single", values: []string{good}, code: http.StatusOK},
+		{name: "identical", values: []string{good, good}, code: http.StatusOK},
+		{name: "identical with space", values: []string{good, " " + good}, code: http.StatusOK},
+		{name: "differing", values: []string{bad, good}, code: http.StatusBadRequest, msg: "Ambiguous webhook HMAC\n"},
+		{name: "differing with empty", values: []string{"", good}, code: http.StatusOK},
+		{name: "try all", values: []string{bad, good}, opts: []Option{WithTryAllHMACHeaders()}, code: http.StatusOK},
+		{name: "try all last", values: []string{good, bad}, opts: []Option{WithTryAllHMACHeaders()}, code: http.StatusOK},
+		{name: "try all stream", values: []string{bad, good}, opts: []Option{WithTryAllHMACHeaders(), WithBodyCopy(false)}, code: http.StatusOK},
+		{name: "try all none", values: []string{bad, "malformed"}, opts: []Option{WithTryAllHMACHeaders()}, code: http.StatusBadRequest, msg: "Invalid webhook signature\n"},
+	}
+
+	for _, tt := range tests {
+		rec := serveHMACHeaders(tt.values, tt.opts...)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+
+		if tt.msg != "" && rec.Body.String() != tt.msg {
+			t.Errorf("%s: expected body %q got %q", tt.name, tt.msg, rec.Body.String())
+		}
+	}
+}
+
+// Test the ambiguous HMAC error matches the sentinel, before the body is read.
+func TestHMACHeadersAmbiguous(t *testing.T) {
+	body := `{"key":"value"}`
+	tr := &trackingReader{Reader: bytes.NewBufferString(body)}
+	req := newWebhookRequest(tr, "example.myshopify.com", sign("secret", body))
+	req.Header.Add("X-Shopify-Hmac-Sha256", sign("other", body))
+
+	if err := serveForError(req); !errors.Is(err, ErrAmbiguousHMAC) {
+		t.Errorf("expected error %v got %v", ErrAmbiguousHMAC, err)
+	}
+
+	if tr.read {
+		t.Errorf("expected body to not be read but it was")
+	}
+}
@@ -2,23 +2,140 @@ package http_shopify_webhook
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/base64"
-	"io/ioutil"
+	"errors"
+	"fmt"
+	"hash"
 	"net/http"
+	"strings"
 )
 
 // Public webhook verify function wrapper.
 // Can be used with any framework tapping into net/http.
 // Simply pass in the secret key for the Shopify app.
+// The restored body is only valid until the next handler returns.
+// Panics if the key is empty, such as from an unset environment variable.
 // Example: `WebhookVerify("abc123", anotherHandler)`.
-func WebhookVerify(key string, fn http.HandlerFunc) http.HandlerFunc {
+func WebhookVerify(key string, fn http.HandlerFunc, opts ...Option) http.HandlerFunc {
+	return webhookVerify(staticKeys(key), fn, opts)
+}
+
+// Webhook verify function wrapper accepting any of several secret keys.
+// Allows rotating the secret without downtime: deploy with both the old and new keys,
+// then remove the old one. Every key is tried, each compared in constant time.
+// Panics if there are no keys or any of them is empty.
+// Example: `WebhookVerifyMulti([]string{"new123", "old123"}, anotherHandler)`.
+func WebhookVerifyMulti(keys []string, fn http.HandlerFunc, opts ...Option) http.HandlerFunc {
+	return webhookVerify(staticKeys(keys...), fn, opts)
+}
+
+// Webhook verify function wrapper with a secret lookup per shop.
+// The lookup receives the shop domain from the request and returns the secret,
+// or false if the shop is unknown, in which case a 401 is returned.
+// An empty secret is treated as an unknown shop.
+// Example: `WebhookVerifyFunc(secretForShop, anotherHandler)`.
+func WebhookVerifyFunc(lookup func(shop string) (string, bool), fn http.HandlerFunc, opts ...Option) http.HandlerFunc {
+	return webhookVerify(func(shop string) ([]string, bool) {
+		key, ok := lookup(shop)
+		if key == "" {
+			// Nothing could verify with an empty key.
+			return nil, false
+		}
+		return []string{key}, ok
+	}, fn, opts)
+}
+
+// Webhook verify middleware with the secret taken from the request context,
+// such as one put there by an earlier auth middleware in a multi-tenant gateway.
+// The secret is extracted per request; if it is missing or empty, a 401 is returned
+// without reading the body, the same as an unknown shop.
+// Example: `WebhookVerifyFromContext(func(ctx context.Context) (string, bool) { return tenantSecret(ctx) })(handler)`.
+func WebhookVerifyFromContext(extract func(ctx context.Context) (string, bool), opts ...Option) Middleware {
+	cfg := newConfig(opts)
+
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			lookup := func(shop string) ([]string, bool) {
+				key, ok := extract(r.Context())
+				if !ok || key == "" {
+					// Nothing could verify without a key.
+					return nil, false
+				}
+				return []string{key}, true
+			}
+			webhookVerifyConfig(lookup, cfg, fn)(w, r)
+		}
+	}
+}
+
+// Build the verify function wrapper for the secret lookup.
+func webhookVerify(lookup secretLookup, fn http.HandlerFunc, opts []Option) http.HandlerFunc {
+	return webhookVerifyConfig(lookup, newConfig(opts), fn)
+}
+
+// Build the verify function wrapper for the secret lookup with an already built config.
+func webhookVerifyConfig(lookup secretLookup, cfg *config, fn http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Verify and if all is well, run the next handler.
-		ok := WebhookVerifyRequest(key, w, r)
-		if ok {
-			fn(w, r)
+		// Body is copied into a buffer of its own, which the next handler may keep.
+		buf := new(bytes.Buffer)
+
+		// Verify and if all is well, run the next handler or queue it.
+		r, ok := verifyHTTPRequest(lookup, cfg, buf, w, r)
+		if !ok {
+			return
+		}
+		if r.Context().Err() != nil {
+			// Client went away during the lookups, nobody is left to respond to.
+			// Left for the retry, which would otherwise be taken as a duplicate.
+			unmarkDelivery(cfg, r)
+			return
+		}
+		if cfg.async != nil && queueable(cfg, r) {
+			serveAsync(cfg, buf.Bytes(), w, r)
+			return
+		}
+		serveDeduped(cfg, fn, w, r)
+	}
+}
+
+// Webhook verify middleware in the standard `func(http.Handler) http.Handler` form.
+// Composes with routers and middleware stacks such as chi, alice, and gorilla.
+// Example: `r.Use(StdMiddleware("abc123"))`.
+func StdMiddleware(key string, opts ...Option) func(http.Handler) http.Handler {
+	return func(h http.Handler) http.Handler {
+		return WebhookVerify(key, h.ServeHTTP, opts...)
+	}
+}
+
+// Register the handler on the mux at the pattern, behind the webhook verification.
+// Example: `Register(mux, "/webhook/order-create", "abc123", ordersHandler)`.
+func Register(mux *http.ServeMux, pattern string, key string, h http.Handler, opts ...Option) {
+	mux.Handle(pattern, WebhookVerify(key, h.ServeHTTP, opts...))
+}
+
+// Resolves the secret keys for a shop, false if the shop is unknown.
+type secretLookup func(shop string) ([]string, bool)
+
+// Secret lookup which always returns the same keys.
+// Panics without a usable key, as no webhook could ever verify.
+func staticKeys(keys ...string) secretLookup {
+	mustKeys(keys)
+	return func(shop string) ([]string, bool) {
+		return keys, true
+	}
+}
+
+// Panic if there are no keys or any of them is empty.
+func mustKeys(keys []string) {
+	if len(keys) == 0 {
+		panic("http_shopify_webhook: " + ErrMissingSecret.Error() + ", no secret keys were passed")
+	}
+	for _, key := range keys {
+		if key == "" {
+			panic("http_shopify_webhook: " + ErrMissingSecret.Error() + ", check the app's secret key is set")
 		}
 	}
 }
@@ -26,39 +143,251 @@ func WebhookVerify(key string, fn http.HandlerFunc) http.HandlerFunc {
 // Webhook verify request from HTTP.
 // Returns a usable handler.
 // Pass in the secret key for the Shopify app and the next handler.`
-func WebhookVerifyRequest(key string, w http.ResponseWriter, r *http.Request) (ok bool) {
+// Values such as the shop are only added to the request context by `WebhookVerify`.
+// When not ok, a response was already written and the request should not be handled further.
+// Only verified requests are ok, so options passing unverified ones through, such as
+// `WithAllowInsecure`, `WithEnforceTopics`, and `WithSkipVerification`, are ignored.
+// Panics if the key is empty, the same as `WebhookVerify`.
+func WebhookVerifyRequest(key string, w http.ResponseWriter, r *http.Request, opts ...Option) (ok bool) {
+	cfg := newConfig(opts)
+	cfg.passThrough = false
+	cfg.allowInsecure = false
+	cfg.enforceTopics = nil
+	cfg.skip = nil
+
+	_, ok = verifyHTTPRequest(staticKeys(key), cfg, new(bytes.Buffer), w, r)
+	return
+}
+
+// Verify the request from HTTP with an already built config.
+// On success, returns the request with the verified values in its context.
+// On failure, the error handler writes the response.
+// The body is read into the buffer, which backs the restored body.
+func verifyHTTPRequest(lookup secretLookup, cfg *config, buf *bytes.Buffer, w http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	if skipVerification(cfg, r) {
+		// Passed through as is, without being verified.
+		return r, true
+	}
+
+	r, finish := startTrace(cfg, r)
+	r, n, err := verifyHTTP(lookup, cfg, buf, w, r)
+	finish(err)
+	setDebugVerified(cfg, w, err)
+	if n != bodyNotRead {
+		// Observed for every request whose body was read, verified or not.
+		cfg.metrics.ObserveBodySize(int(n))
+	}
+	if err != nil {
+		logFailure(cfg, r, int(max(n, 0)), err)
+		cfg.metrics.IncRejected(rejectReason(err))
+		if cfg.passThrough || (!enforcedTopic(cfg, r) && passableFailure(err)) {
+			// Left to the next handler to decide on.
+			return passThrough(r, err), true
+		}
+		cfg.errorHandler(w, r, err)
+		return r, false
+	}
+	cfg.metrics.IncVerified(r.Header.Get("X-Shopify-Topic"))
+	teeBody(cfg, r, buf.Bytes())
+	onSuccess(cfg, r, buf.Bytes())
+
+	r, dup := isDuplicate(cfg, r)
+	if dup {
+		logDuplicate(cfg, r)
+
+		// Already delivered, acknowledge without handling it again.
+		w.WriteHeader(http.StatusOK)
+		return r, false
+	}
+
+	return r, true
+}
+
+// Size returned by `verifyHTTP` when it failed before the body was read.
+const bodyNotRead = -1
+
+// Do the verification of the request from HTTP.
+// Returns the number of body bytes read, or `bodyNotRead`, and the reason for the failure, if any.
+// All checks on the headers are done before the body is touched,
+// so requests which can never verify do not cost a body read.
+func verifyHTTP(lookup secretLookup, cfg *config, buf *bytes.Buffer, w http.ResponseWriter, r *http.Request) (*http.Request, int64, error) {
+	if err := beforeVerify(cfg, r); err != nil {
+		return r, bodyNotRead, err
+	}
+	if err := checkMethod(cfg, w, r); err != nil {
+		return r, bodyNotRead, err
+	}
+	if err := checkContentType(cfg, r); err != nil {
+		return r, bodyNotRead, err
+	}
+	if err := checkHeaderLengths(cfg, r); err != nil {
+		return r, bodyNotRead, err
+	}
+
 	// HMAC from request headers and the shop.
-	shmac := r.Header.Get("X-Shopify-Hmac-Sha256")
-	shop := r.Header.Get("X-Shopify-Shop-Domain")
+	shmacs, herr := hmacHeaders(cfg, r)
+	shop := r.Header.Get(cfg.shopHeader)
+	if shop == "" {
+		// No shop provided, nothing to look up.
+		return r, bodyNotRead, ErrMissingShop
+	}
+	if herr != nil {
+		// Sent more than once, with no way to tell which is right.
+		return r, bodyNotRead, herr
+	}
+	if len(shmacs) == 0 {
+		// No HMAC provided, skip reading the body.
+		return r, bodyNotRead, ErrMissingHMAC
+	}
 
-	// Read the body and put it back.
-	bb, _ := ioutil.ReadAll(r.Body)
-	r.Body.Close()
-	r.Body = ioutil.NopCloser(bytes.NewBuffer(bb))
+	if err := checkShopDomain(cfg, shop); err != nil {
+		return r, bodyNotRead, err
+	}
 
-	// Verify all is ok.
-	ok = verifyRequest(key, shop, shmac, bb)
-	if !ok {
-		http.Error(w, "Invalid webhook signature", http.StatusBadRequest)
-		return
+	// Resolve the secret for the shop.
+	keys, found := lookup(shop)
+	if !found {
+		// Unknown shop, skip reading the body.
+		return r, bodyNotRead, ErrUnknownShop
 	}
 
-	return
+	// Reject stale webhooks before doing any work on the body.
+	if err := checkAge(cfg, r); err != nil {
+		return r, bodyNotRead, err
+	}
+
+	// Read the body, put it back, and verify all is ok.
+	n, err := verifyBody(cfg, buf, keys, shop, shmacs, w, r)
+	if err != nil {
+		return r, n, err
+	}
+
+	// Signature is good, but the shop may still not be one we expect.
+	if err := checkAllowedShop(cfg, shop); err != nil {
+		return r, n, err
+	}
+	if err := checkRequiredHeaders(cfg, r); err != nil {
+		return r, n, err
+	}
+	if err := checkRateLimit(cfg, r, shop); err != nil {
+		return r, n, err
+	}
+
+	// Pass the verified values along to the next handlers.
+	ctx := context.WithValue(r.Context(), verifiedKey, true)
+	ctx = context.WithValue(ctx, shopKey, shop)
+	ctx = context.WithValue(ctx, clientIPKey, clientIP(cfg, r))
+	if topic := r.Header.Get("X-Shopify-Topic"); topic != "" {
+		ctx = context.WithValue(ctx, topicKey, topic)
+	}
+	if version := r.Header.Get(apiVersionHeader); version != "" {
+		ctx = context.WithValue(ctx, apiVersionKey, version)
+	}
+
+	return r.WithContext(ctx), n, nil
+}
+
+// Verify the request data.
+// Ensures a shop and HMAC were provided before checking the signature.
+// Returns the reason for the failure, if any.
+func verifyRequest(key string, shop string, shmac string, bb []byte) error {
+	return verifyRequestKeys(sha256.New, decodeHMAC, []string{key}, shop, shmac, bb)
+}
+
+// Verify the request data against each of the keys, using the hash and the HMAC decoder.
+func verifyRequestKeys(newHash func() hash.Hash, decode func(string, int) ([]byte, error), keys []string, shop string, shmac string, bb []byte) error {
+	if shop == "" {
+		// No shop provided.
+		return ErrMissingShop
+	}
+	if shmac == "" {
+		// No HMAC provided.
+		return ErrMissingHMAC
+	}
+
+	return verifyKeys(newHash, decode, keys, shmac, bb)
+}
+
+// Verify the HMAC of the body against each of the keys.
+// All keys are tried, so the time taken does not reveal which one matched.
+func verifyKeys(newHash func() hash.Hash, decode func(string, int) ([]byte, error), keys []string, shmac string, bb []byte) error {
+	err := ErrInvalidSignature
+	for _, key := range keys {
+		if kerr := verifyHash(newHash, decode, key, shmac, bb); kerr == nil {
+			err = nil
+		} else if err != nil {
+			err = kerr
+		}
+	}
+
+	return err
 }
 
 // Do the actual work.
 // Take the request body, the secret key,
 // Attempt to reproduce the same HMAC from the request.
-func verifyRequest(key string, shop string, shmac string, bb []byte) bool {
-	if shop == "" {
-		// No shop provided.
-		return false
+// Shopify sends the HMAC as a base64 encoded digest, so a hex encoded
+// or otherwise malformed header will simply not match and is rejected.
+// Usable outside of HTTP, such as for webhooks pulled from a queue.
+// Example: `ok := Verify("abc123", hmacHeader, body)`.
+func Verify(key string, shmac string, bb []byte) bool {
+	return verifyHash(sha256.New, decodeHMAC, key, shmac, bb) == nil
+}
+
+// Verify the HMAC of the body, created with the hash and decoded with the decoder.
+// Fails with `ErrInvalidSignature`, wrapped with the cause for a malformed HMAC.
+func verifyHash(newHash func() hash.Hash, decode func(string, int) ([]byte, error), key string, shmac string, bb []byte) error {
+	// Decode the HMAC from Shopify to raw bytes.
+	// Checked before hashing the body, which is only worth doing for a digest of the right size.
+	h := hmac.New(newHash, []byte(key))
+	dec, err := decode(shmac, h.Size())
+	if err != nil {
+		// Not a valid base64 string, or not a digest.
+		return fmt.Errorf("%w: malformed HMAC: %v", ErrInvalidSignature, err)
 	}
 
 	// Create an hmac of the body with the secret key to compare.
-	h := hmac.New(sha256.New, []byte(key))
+	// Comparison is done in constant time to avoid leaking timing information.
+	h.Write(bb)
+	if !hmac.Equal(h.Sum(nil), dec) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// Decode the base64 HMAC to raw bytes, to compare with the digest of the size.
+// Whitespace around it, such as a newline added by a proxy, and missing padding
+// are tolerated. Only the encoding is relaxed, the bytes must still match exactly.
+// HMACs which cannot decode to the size are rejected without decoding them.
+func decodeHMAC(shmac string, size int) ([]byte, error) {
+	shmac = strings.TrimRight(strings.TrimSpace(shmac), "=")
+	if len(shmac) != base64.RawStdEncoding.EncodedLen(size) {
+		return nil, errHMACLength
+	}
+
+	return base64.RawStdEncoding.DecodeString(shmac)
+}
+
+// HMAC which cannot be a digest of the expected size.
+var errHMACLength = errors.New("length does not match the digest")
+
+// Compute the HMAC of the body with the secret key, encoded as Shopify sends it.
+// Useful for signing requests in tests of handlers which sit behind the verifier.
+// Example: `req.Header.Set("X-Shopify-Hmac-Sha256", ComputeHMAC("abc123", body))`.
+func ComputeHMAC(key string, bb []byte) string {
+	return base64.StdEncoding.EncodeToString(digest(key, bb))
+}
+
+// Create the raw HMAC digest of the body with the secret key.
+func digest(key string, bb []byte) []byte {
+	return digestHash(sha256.New, key, bb)
+}
+
+// Create the raw HMAC digest of the body with the secret key, using the hash.
+func digestHash(newHash func() hash.Hash, key string, bb []byte) []byte {
+	h := hmac.New(newHash, []byte(key))
 	h.Write(bb)
-	enc := base64.StdEncoding.EncodeToString(h.Sum(nil))
 
-	return enc == shmac
+	return h.Sum(nil)
 }
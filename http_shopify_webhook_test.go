@@ -2,10 +2,19 @@ package http_shopify_webhook
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/go-chi/chi/v5"
 )
 
 // Test base verification function works.
@@ -15,8 +24,8 @@ func TestVerifyRequest(t *testing.T) {
 	hmac := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
 	shop := "example.myshopify.com"
 
-	if ok := verifyRequest("secret", shop, hmac, body); !ok {
-		t.Errorf("expected request data to verify")
+	if err := verifyRequest("secret", shop, hmac, body); err != nil {
+		t.Errorf("expected request data to verify got %v", err)
 	}
 }
 
@@ -26,7 +35,7 @@ func TestVerifyRequestError(t *testing.T) {
 	hmac := "ee2012a00f1649bc35f4cfe1fa582b2ebda5cbf2ef82713d6dc2ec93d81f96fb"
 	shop := ""
 
-	if ok := verifyRequest("secret", shop, hmac, body); ok {
+	if err := verifyRequest("secret", shop, hmac, body); err == nil {
 		t.Errorf("expected request data to not verify, but it did")
 	}
 
@@ -34,11 +43,227 @@ func TestVerifyRequestError(t *testing.T) {
 	shop = "example.myshopify.com"
 	hmac = "7iASoA8WSbw19M/h+"
 
-	if ok := verifyRequest("secret", shop, hmac, body); ok {
+	if err := verifyRequest("secret", shop, hmac, body); err == nil {
 		t.Errorf("expected request data to not verify, but it did")
 	}
 }
 
+// Test each failure of the verification returns its reason.
+func TestVerifyRequestReasons(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+	hmac := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
+	shop := "example.myshopify.com"
+
+	tests := []struct {
+		name string
+		key  string
+		shop string
+		hmac string
+		err  error
+	}{
+		{name: "missing shop", key: "secret", shop: "", hmac: hmac, err: ErrMissingShop},
+		{name: "missing hmac", key: "secret", shop: shop, hmac: "", err: ErrMissingHMAC},
+		{name: "malformed hmac", key: "secret", shop: shop, hmac: "not base64!", err: ErrInvalidSignature},
+		{name: "wrong key", key: "other", shop: shop, hmac: hmac, err: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		if err := verifyRequest(tt.key, tt.shop, tt.hmac, body); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, err)
+		}
+	}
+
+	// A malformed HMAC says so.
+	err := verifyRequest("secret", shop, "not base64!", body)
+	if err == ErrInvalidSignature || !strings.Contains(err.Error(), "malformed HMAC") {
+		t.Errorf("expected a wrapped malformed HMAC error got %v", err)
+	}
+
+	// Any matching key verifies.
+	if err := verifyRequestKeys(sha256.New, decodeHMAC, []string{"other", "secret"}, shop, hmac, body); err != nil {
+		t.Errorf("expected request data to verify with the second key got %v", err)
+	}
+}
+
+// Test the standalone verification function.
+func TestVerify(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+
+	if ok := Verify("secret", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=", body); !ok {
+		t.Errorf("expected body to verify")
+	}
+
+	if ok := Verify("secret", "7iASoA8WSbw19M/h+", body); ok {
+		t.Errorf("expected body to not verify, but it did")
+	}
+}
+
+// Test the computed HMAC matches the one Shopify sends.
+func TestComputeHMAC(t *testing.T) {
+	exp := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
+	if h := ComputeHMAC("secret", []byte(`{"key":"value"}`)); h != exp {
+		t.Errorf("expected HMAC %q got %q", exp, h)
+	}
+}
+
+// Test verification against a set of known requests.
+func TestVerifyRequestTable(t *testing.T) {
+	tests := []struct {
+		name string
+		key  string
+		shop string
+		hmac string
+		body string
+		ok   bool
+	}{
+		{
+			name: "simple body",
+			key:  "secret",
+			shop: "example.myshopify.com",
+			hmac: "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=",
+			body: `{"key":"value"}`,
+			ok:   true,
+		},
+		{
+			name: "captured order",
+			key:  "hush",
+			shop: "johns-apparel.myshopify.com",
+			hmac: "d+j9YjJOa6amqi1YL0xYNhCWKYlzgT/pt4fJthxMpG0=",
+			body: `{"id":820982911946154508,"email":"jon@doe.ca","closed_at":null,"created_at":"2019-04-01T12:00:00-04:00","total_price":"403.00","currency":"USD"}`,
+			ok:   true,
+		},
+		{
+			name: "hex encoded",
+			key:  "secret",
+			shop: "example.myshopify.com",
+			hmac: "ee2012a00f1649bc35f4cfe1fa582b2ebda5cbf2ef82713d6dc2ec93d81f96fb",
+			body: `{"key":"value"}`,
+			ok:   false,
+		},
+		{
+			name: "malformed base64",
+			key:  "secret",
+			shop: "example.myshopify.com",
+			hmac: "not*valid*base64!",
+			body: `{"key":"value"}`,
+			ok:   false,
+		},
+		{
+			name: "empty hmac",
+			key:  "secret",
+			shop: "example.myshopify.com",
+			hmac: "",
+			body: ``,
+			ok:   false,
+		},
+		{
+			name: "wrong key",
+			key:  "other",
+			shop: "example.myshopify.com",
+			hmac: "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=",
+			body: `{"key":"value"}`,
+			ok:   false,
+		},
+	}
+
+	for _, tt := range tests {
+		if ok := verifyRequest(tt.key, tt.shop, tt.hmac, []byte(tt.body)) == nil; ok != tt.ok {
+			t.Errorf("%s: expected verification to be %v got %v", tt.name, tt.ok, ok)
+		}
+	}
+}
+
+// Test a HMAC differing only in the last byte is rejected.
+func TestVerifyRequestNearMiss(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+	shop := "example.myshopify.com"
+
+	// Flip the last byte of the real digest.
+	dec, _ := base64.StdEncoding.DecodeString("7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+	dec[len(dec)-1] ^= 0x01
+	hmac := base64.StdEncoding.EncodeToString(dec)
+
+	if err := verifyRequest("secret", shop, hmac, body); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected near-miss HMAC to not verify with %v got %v", ErrInvalidSignature, err)
+	}
+}
+
+// Test whitespace and padding variations of a valid HMAC are accepted.
+func TestVerifyHMACWhitespace(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+	hmac := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
+
+	for _, h := range []string{hmac + "\n", hmac + "\r\n", "  " + hmac, "\t" + hmac + " ", strings.TrimRight(hmac, "=")} {
+		if ok := Verify("secret", h, body); !ok {
+			t.Errorf("expected %q to verify but did not", h)
+		}
+	}
+
+	// Relaxing the encoding does not accept other digests.
+	for _, h := range []string{" 7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLtk9gflvs=\n", hmac[:len(hmac)-2] + "\n", " \n "} {
+		if ok := Verify("secret", h, body); ok {
+			t.Errorf("expected %q to not verify but did", h)
+		}
+	}
+}
+
+// Test a HMAC header with a trailing newline is verified by the middleware.
+func TestNetHttpHMACWhitespace(t *testing.T) {
+	body := `{"key":"value"}`
+	for _, h := range []string{sign("secret", body) + "\n", "  " + sign("secret", body)} {
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", h)
+		if err := serveForError(req); err != nil {
+			t.Errorf("expected %q to verify got %v", h, err)
+		}
+	}
+
+	// A header of only whitespace is missing.
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", " \n")
+	if err := serveForError(req); !errors.Is(err, ErrMissingHMAC) {
+		t.Errorf("expected error %v got %v", ErrMissingHMAC, err)
+	}
+}
+
+// Test a HMAC of the wrong length is rejected before decoding.
+func TestVerifyHMACLength(t *testing.T) {
+	body := []byte(`{"key":"value"}`)
+	shop := "example.myshopify.com"
+	hmac := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
+
+	tests := []struct {
+		name string
+		hmac string
+	}{
+		{name: "over-long", hmac: hmac + hmac},
+		{name: "under-long", hmac: hmac[:20]},
+		{name: "one extra character", hmac: strings.TrimRight(hmac, "=") + "A"},
+		{name: "hex encoded", hmac: "ee2012a00f1649bc35f4cfe1fa582b2ebda5cbf2ef82713d6dc2ec93d81f96fb"},
+	}
+
+	for _, tt := range tests {
+		err := verifyRequest("secret", shop, tt.hmac, body)
+		if !errors.Is(err, ErrInvalidSignature) {
+			t.Errorf("%s: expected error %v got %v", tt.name, ErrInvalidSignature, err)
+		}
+
+		if err == nil || !strings.Contains(err.Error(), errHMACLength.Error()) {
+			t.Errorf("%s: expected length error got %v", tt.name, err)
+		}
+	}
+}
+
+// Benchmark the base verification function.
+func BenchmarkVerifyRequest(b *testing.B) {
+	body := []byte(`{"key":"value"}`)
+	hmac := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
+	shop := "example.myshopify.com"
+
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		verifyRequest("secret", shop, hmac, body)
+	}
+}
+
 // Test the implementation with a server.
 func TestNetHttpSuccess(t *testing.T) {
 	// Set our data.
@@ -79,6 +304,260 @@ func TestNetHttpFailure(t *testing.T) {
 	}
 }
 
+// Test the secret is resolved per shop.
+func TestWebhookVerifyFunc(t *testing.T) {
+	body := `{"key":"value"}`
+	secrets := map[string]string{"example.myshopify.com": "secret"}
+	lookup := func(shop string) (string, bool) {
+		key, ok := secrets[shop]
+		return key, ok
+	}
+
+	// Serves a request from the shop, signed with the key.
+	serve := func(shop string, key string) (int, bool) {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+		req.Header.Set("X-Shopify-Shop-Domain", shop)
+		req.Header.Set("X-Shopify-Hmac-Sha256", sign(key, body))
+
+		ran := false
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+		})
+
+		rec := httptest.NewRecorder()
+		WebhookVerifyFunc(lookup, nh).ServeHTTP(rec, req)
+
+		return rec.Code, ran
+	}
+
+	// Known shop.
+	if c, ran := serve("example.myshopify.com", "secret"); c != http.StatusOK || !ran {
+		t.Errorf("expected known shop to verify got status code %v", c)
+	}
+
+	// Unknown shop.
+	if c, ran := serve("unknown.myshopify.com", "secret"); c != http.StatusUnauthorized || ran {
+		t.Errorf("expected status code %v for unknown shop got %v", http.StatusUnauthorized, c)
+	}
+
+	// Rotate the secret, the old one should no longer verify.
+	secrets["example.myshopify.com"] = "rotated"
+	if c, ran := serve("example.myshopify.com", "secret"); c != http.StatusBadRequest || ran {
+		t.Errorf("expected status code %v for old secret got %v", http.StatusBadRequest, c)
+	}
+
+	if c, ran := serve("example.myshopify.com", "rotated"); c != http.StatusOK || !ran {
+		t.Errorf("expected rotated secret to verify got status code %v", c)
+	}
+}
+
+// Test the standard middleware slots into a chi router.
+func TestStdMiddlewareChi(t *testing.T) {
+	body := `{"key":"value"}`
+
+	ran := false
+	r := chi.NewRouter()
+	r.Use(StdMiddleware("secret"))
+	r.Post("/webhook/order-create", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		fmt.Fprintf(w, "Ok")
+	})
+
+	// Signed request.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", sign("secret", body))
+	r.ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK || !ran {
+		t.Errorf("expected status code %v and next handler to run got %v", http.StatusOK, c)
+	}
+
+	// Badly signed request.
+	ran = false
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", sign("other", body))
+	r.ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest || ran {
+		t.Errorf("expected status code %v and next handler to not run got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test any of the keys verifies the request.
+func TestWebhookVerifyMulti(t *testing.T) {
+	body := `{"key":"value"}`
+	keys := []string{"new", "old"}
+
+	for _, key := range []string{"new", "old", "other"} {
+		ran := false
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+		})
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		req.Header.Set("X-Shopify-Hmac-Sha256", sign(key, body))
+		WebhookVerifyMulti(keys, nh).ServeHTTP(rec, req)
+
+		exp := key != "other"
+		if ran != exp {
+			t.Errorf("%s: expected next handler to run to be %v got %v", key, exp, ran)
+		}
+	}
+}
+
+// Response writer which counts the calls to WriteHeader.
+type countingWriter struct {
+	*httptest.ResponseRecorder
+	headers int
+}
+
+func (cw *countingWriter) WriteHeader(code int) {
+	cw.headers++
+	cw.ResponseRecorder.WriteHeader(code)
+}
+
+// Test a failed verification writes exactly one response and stops the chain.
+func TestNetHttpFailureAbortsChain(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(`{"key":"value"}`))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+")
+
+	// Our "next" handler, which should never run.
+	ran := false
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		w.WriteHeader(http.StatusOK)
+	})
+
+	cw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	WebhookVerify("secret", nh).ServeHTTP(cw, req)
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+
+	if cw.headers != 1 {
+		t.Errorf("expected exactly one response to be written got %v", cw.headers)
+	}
+
+	if c := cw.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test the next handler can read the full body after verification.
+func TestNetHttpBodyRestored(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(`{"key":"value"}`))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	// Our "next" handler, decoding the body.
+	var payload map[string]string
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Errorf("expected body to decode got %v", err)
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nh).ServeHTTP(rec, req)
+
+	if v := payload["key"]; v != "value" {
+		t.Errorf("expected payload key to be %q got %q", "value", v)
+	}
+}
+
+// Test a partially read body is verified and restored from where it was left.
+func TestNetHttpBodyPartiallyRead(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(`{"key":"value"}`))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "6rH4EJql5/KuczyHcMYXFxYRTBh6GqbV730y0nJb4vQ=")
+
+	// Consume the start of the body before verification.
+	req.Body.Read(make([]byte, 2))
+
+	var body []byte
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = ioutil.ReadAll(r.Body)
+	})
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nh).ServeHTTP(rec, req)
+
+	if b := string(body); b != `key":"value"}` {
+		t.Errorf("expected remaining body to be restored got %q", b)
+	}
+}
+
+// Test a nil body is treated as empty.
+func TestNetHttpNilBody(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPost, "/webhook/order-create", nil)
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "+eZuF5tnR65UEI+C+K3os8Jddv0wr95sOVgixTAZYWk=")
+
+	ran := false
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		if r.Body == nil {
+			t.Errorf("expected body to be restored but it was nil")
+		}
+	})
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nh).ServeHTTP(rec, req)
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Reader which returns some data, then an error.
+type errReader struct {
+	read bool
+}
+
+func (er *errReader) Read(p []byte) (int, error) {
+	if !er.read {
+		er.read = true
+		return copy(p, `{"key":`), nil
+	}
+
+	return 0, errors.New("connection reset")
+}
+
+// Test a body read error is reported instead of a signature mismatch.
+func TestNetHttpBodyReadError(t *testing.T) {
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", &errReader{})
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	ran := false
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	})
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nh).ServeHTTP(rec, req)
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if b := rec.Body.String(); !strings.Contains(b, "Unable to read webhook body") {
+		t.Errorf("expected body read error message got %q", b)
+	}
+}
+
 // Sets up the server for a few tests.
 func setupServer(key string, shop string, hmac string, body string) (*httptest.ResponseRecorder, bool) {
 	// Create a mock request to use
@@ -102,3 +581,129 @@ func setupServer(key string, shop string, hmac string, body string) (*httptest.R
 
 	return rec, ran
 }
+
+// Test a handler registered on a mux is verified before running.
+func TestRegister(t *testing.T) {
+	ran := 0
+	mux := http.NewServeMux()
+	Register(mux, "/webhook/order-create", "secret", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran++
+	}), WithMaxBodySize(1<<10))
+
+	body := `{"key":"value"}`
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)))
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	rec = httptest.NewRecorder()
+	mux.ServeHTTP(rec, newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)))
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if ran != 1 {
+		t.Errorf("expected handler to run once got %v", ran)
+	}
+}
+
+// Assert the function panics with a message about the secret.
+func assertMissingSecretPanic(t *testing.T, name string, fn func()) {
+	t.Helper()
+	defer func() {
+		rec := recover()
+		if rec == nil {
+			t.Errorf("%s: expected a panic for an empty key but did not", name)
+			return
+		}
+
+		if msg, _ := rec.(string); !strings.Contains(msg, ErrMissingSecret.Error()) {
+			t.Errorf("%s: expected panic about the missing secret got %v", name, rec)
+		}
+	}()
+	fn()
+}
+
+// Test an empty key is caught when building the verifier.
+func TestEmptyKeyPanics(t *testing.T) {
+	nh := func(w http.ResponseWriter, r *http.Request) {}
+
+	assertMissingSecretPanic(t, "WebhookVerify", func() { WebhookVerify("", nh) })
+	assertMissingSecretPanic(t, "WebhookVerifyMulti", func() { WebhookVerifyMulti([]string{"secret", ""}, nh) })
+	assertMissingSecretPanic(t, "WebhookVerifyMulti without keys", func() { WebhookVerifyMulti(nil, nh) })
+	assertMissingSecretPanic(t, "StdMiddleware", func() { StdMiddleware("")(http.HandlerFunc(nh)) })
+	assertMissingSecretPanic(t, "NewDispatcher", func() { NewDispatcher("") })
+}
+
+// Test an empty secret from the lookup is treated as an unknown shop.
+func TestEmptyKeyLookup(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("", body))
+
+	rec := httptest.NewRecorder()
+	WebhookVerifyFunc(func(shop string) (string, bool) {
+		return "", true
+	}, func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusUnauthorized {
+		t.Errorf("expected status code %v got %v", http.StatusUnauthorized, c)
+	}
+}
+
+// Key type for the secret put in the context by a test middleware.
+type secretCtxKey struct{}
+
+// Get the secret put in the context by a test middleware.
+func secretFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(secretCtxKey{}).(string)
+	return key, ok
+}
+
+// Test the secret is taken from the context set by an earlier middleware.
+func TestWebhookVerifyFromContext(t *testing.T) {
+	body := `{"key":"value"}`
+	withSecret := func(key string) Middleware {
+		return func(fn http.HandlerFunc) http.HandlerFunc {
+			return func(w http.ResponseWriter, r *http.Request) {
+				fn(w, r.WithContext(context.WithValue(r.Context(), secretCtxKey{}, key)))
+			}
+		}
+	}
+
+	tests := []struct {
+		name string
+		mws  []Middleware
+		code int
+		ran  bool
+	}{
+		{name: "secret", mws: []Middleware{withSecret("secret")}, code: http.StatusOK, ran: true},
+		{name: "wrong secret", mws: []Middleware{withSecret("other")}, code: http.StatusBadRequest},
+		{name: "empty secret", mws: []Middleware{withSecret("")}, code: http.StatusUnauthorized},
+		{name: "no secret", code: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		tr := &trackingReader{Reader: bytes.NewBufferString(body)}
+		req := newWebhookRequest(tr, "example.myshopify.com", sign("secret", body))
+
+		ran := false
+		mws := append(tt.mws, WebhookVerifyFromContext(secretFromContext))
+		rec := httptest.NewRecorder()
+		Chain(mws...)(func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+		}).ServeHTTP(rec, req)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+
+		if ran != tt.ran {
+			t.Errorf("%s: expected next handler to run %v got %v", tt.name, tt.ran, ran)
+		}
+
+		if tt.code == http.StatusUnauthorized && tr.read {
+			t.Errorf("%s: expected body to not be read", tt.name)
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package http_shopify_webhook
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+)
+
+// Webhook verify function wrapper which decodes the JSON body for the handler.
+// Bodies which fail to decode into the payload type are rejected with a 400.
+// Example: `VerifyJSON("abc123", func(w http.ResponseWriter, r *http.Request, o Order) { ... })`.
+func VerifyJSON[T any](key string, fn func(w http.ResponseWriter, r *http.Request, payload T), opts ...Option) http.HandlerFunc {
+	return verifyPayload(key, json.Unmarshal, "Invalid webhook payload", fn, opts)
+}
+
+// Webhook verify function wrapper which decodes the body with the function for the handler.
+// Bodies which fail to decode are rejected with a 400 and the message.
+func verifyPayload[T any](key string, decode func([]byte, any) error, msg string, fn func(w http.ResponseWriter, r *http.Request, payload T), opts []Option) http.HandlerFunc {
+	return WebhookVerify(key, func(w http.ResponseWriter, r *http.Request) {
+		// Read the verified body and put it back for the handler.
+		bb, _ := ioutil.ReadAll(r.Body)
+		restoreBody(r, bb)
+
+		var payload T
+		if err := decode(bb, &payload); err != nil {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+
+		fn(w, r, payload)
+	}, opts...)
+}
@@ -0,0 +1,68 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Shape of an `orders/create` webhook, trimmed down.
+type testOrder struct {
+	ID         int64  `json:"id"`
+	Email      string `json:"email"`
+	TotalPrice string `json:"total_price"`
+	LineItems  []struct {
+		Title    string `json:"title"`
+		Quantity int    `json:"quantity"`
+	} `json:"line_items"`
+}
+
+// Test the body is decoded into the payload for the handler.
+func TestVerifyJSON(t *testing.T) {
+	body := `{"id":820982911946154508,"email":"jon@doe.ca","total_price":"403.00","line_items":[{"title":"IPod Nano - 8gb","quantity":1}]}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	var order testOrder
+	rec := httptest.NewRecorder()
+	VerifyJSON("secret", func(w http.ResponseWriter, r *http.Request, o testOrder) {
+		order = o
+	}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if order.ID != 820982911946154508 || order.Email != "jon@doe.ca" || order.TotalPrice != "403.00" {
+		t.Errorf("expected order fields to be populated got %+v", order)
+	}
+
+	if len(order.LineItems) != 1 || order.LineItems[0].Title != "IPod Nano - 8gb" {
+		t.Errorf("expected line items to be populated got %+v", order.LineItems)
+	}
+}
+
+// Test a body which is not valid JSON is rejected with its own message.
+func TestVerifyJSONInvalid(t *testing.T) {
+	body := `{"id":`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	VerifyJSON("secret", func(w http.ResponseWriter, r *http.Request, o testOrder) {
+		ran = true
+	}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if b := rec.Body.String(); !strings.Contains(b, "Invalid webhook payload") {
+		t.Errorf("expected payload error message got %q", b)
+	}
+
+	if ran {
+		t.Errorf("expected handler to not run but it did")
+	}
+}
@@ -0,0 +1,81 @@
+package http_shopify_webhook
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// Log verification failures to the structured logger.
+// Entries include the shop, topic, body size, client IP, and the reason for the failure.
+// Nothing is logged without this option.
+// Example: `WebhookVerify("abc123", handler, WithLogger(slog.Default()))`.
+func WithLogger(logger *slog.Logger) Option {
+	return func(cfg *config) {
+		cfg.logger = logger
+	}
+}
+
+// Log the failed verification, if a logger is set.
+func logFailure(cfg *config, r *http.Request, size int, err error) {
+	if cfg.logger == nil {
+		return
+	}
+
+	cfg.logger.LogAttrs(
+		r.Context(),
+		slog.LevelWarn,
+		"shopify webhook verification failed",
+		slog.String("shop", r.Header.Get(cfg.shopHeader)),
+		slog.String("topic", r.Header.Get("X-Shopify-Topic")),
+		slog.Int("body_size", size),
+		slog.String("client_ip", clientIP(cfg, r)),
+		slog.String("reason", err.Error()),
+	)
+}
+
+// Log the duplicate delivery, if a logger is set.
+func logDuplicate(cfg *config, r *http.Request) {
+	if cfg.logger == nil || !cfg.logger.Enabled(r.Context(), slog.LevelDebug) {
+		return
+	}
+
+	cfg.logger.LogAttrs(
+		r.Context(),
+		slog.LevelDebug,
+		"shopify webhook duplicate skipped",
+		slog.String("shop", r.Header.Get(cfg.shopHeader)),
+		slog.String("webhook_id", r.Header.Get(webhookIDHeader)),
+	)
+}
+
+// Log the failed write of the body to the tee, if a logger is set.
+func logTeeFailure(cfg *config, r *http.Request, err error) {
+	if cfg.logger == nil {
+		return
+	}
+
+	cfg.logger.LogAttrs(
+		r.Context(),
+		slog.LevelError,
+		"shopify webhook body tee failed",
+		slog.String("shop", r.Header.Get(cfg.shopHeader)),
+		slog.String("topic", r.Header.Get("X-Shopify-Topic")),
+		slog.String("reason", err.Error()),
+	)
+}
+
+// Log the failure of the dedup store, if a logger is set.
+func logDedupFailure(cfg *config, r *http.Request, err error) {
+	if cfg.logger == nil {
+		return
+	}
+
+	cfg.logger.LogAttrs(
+		r.Context(),
+		slog.LevelError,
+		"shopify webhook dedup failed",
+		slog.String("shop", r.Header.Get(cfg.shopHeader)),
+		slog.String("webhook_id", r.Header.Get(webhookIDHeader)),
+		slog.String("reason", err.Error()),
+	)
+}
@@ -0,0 +1,122 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// Handler which captures the log records.
+type captureHandler struct {
+	mu      sync.Mutex
+	records []slog.Record
+}
+
+func (h *captureHandler) Enabled(ctx context.Context, l slog.Level) bool {
+	return true
+}
+
+func (h *captureHandler) Handle(ctx context.Context, r slog.Record) error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.records = append(h.records, r)
+
+	return nil
+}
+
+func (h *captureHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h
+}
+
+func (h *captureHandler) WithGroup(name string) slog.Handler {
+	return h
+}
+
+// Get the attributes of the record as strings.
+func recordAttrs(r slog.Record) map[string]string {
+	attrs := make(map[string]string)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs[a.Key] = a.Value.String()
+		return true
+	})
+
+	return attrs
+}
+
+// Test failed verifications are logged with their details.
+func TestWithLogger(t *testing.T) {
+	ch := &captureHandler{}
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithLogger(slog.New(ch)))
+	h.ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(ch.records) != 1 {
+		t.Fatalf("expected one log record got %v", len(ch.records))
+	}
+
+	rec := ch.records[0]
+	if rec.Level != slog.LevelWarn {
+		t.Errorf("expected level %v got %v", slog.LevelWarn, rec.Level)
+	}
+
+	exp := map[string]string{
+		"shop":      "example.myshopify.com",
+		"topic":     "orders/create",
+		"body_size": "15",
+		"client_ip": "192.0.2.1",
+		"reason":    ErrInvalidSignature.Error(),
+	}
+	attrs := recordAttrs(rec)
+	for k, v := range exp {
+		if attrs[k] != v {
+			t.Errorf("expected attribute %s to be %q got %q", k, v, attrs[k])
+		}
+	}
+}
+
+// Test verified requests are not logged.
+func TestWithLoggerSuccess(t *testing.T) {
+	ch := &captureHandler{}
+	serveWithOptions(`{"key":"value"}`, WithLogger(slog.New(ch)))
+
+	if len(ch.records) != 0 {
+		t.Errorf("expected no log records got %v", len(ch.records))
+	}
+}
+
+// Test duplicates are logged at debug level.
+func TestWithLoggerDuplicate(t *testing.T) {
+	ch := &captureHandler{}
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithLogger(slog.New(ch)), WithDeduplication(NewMemoryDedupStore(), time.Hour))
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("abc"))
+	h.ServeHTTP(httptest.NewRecorder(), newDedupRequest("abc"))
+
+	if len(ch.records) != 1 || ch.records[0].Level != slog.LevelDebug {
+		t.Fatalf("expected one debug log record got %v", len(ch.records))
+	}
+
+	if id := recordAttrs(ch.records[0])["webhook_id"]; id != "abc" {
+		t.Errorf("expected webhook ID %q got %q", "abc", id)
+	}
+}
+
+// Test logging does not allocate without a logger.
+func TestLogFailureNoLogger(t *testing.T) {
+	cfg := newConfig(nil)
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", nil)
+
+	allocs := testing.AllocsPerRun(100, func() {
+		logFailure(cfg, req, 0, ErrInvalidSignature)
+	})
+	if allocs != 0 {
+		t.Errorf("expected no allocations got %v", allocs)
+	}
+}
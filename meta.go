@@ -0,0 +1,51 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Header Shopify sends the API version of the webhook payload in.
+const apiVersionHeader = "X-Shopify-API-Version"
+
+// Details of a webhook from its headers.
+// Fields are empty for headers which were not sent.
+type WebhookMeta struct {
+	// Shop domain which sent the webhook.
+	Shop string
+
+	// Topic of the webhook, such as `orders/create`.
+	Topic string
+
+	// API version of the payload, such as `2024-01`.
+	APIVersion string
+
+	// Unique ID of the webhook, the same across retries.
+	WebhookID string
+}
+
+// Verify the request and read its body and details, in one call.
+// Useful outside of a handler chain, such as for queue consumers and tests.
+// On failure, no body is returned and the error is one of the verification reasons,
+// compare with `errors.Is`. Nothing is written in response, that is left to the caller.
+// Panics if the key is empty, the same as `WebhookVerify`.
+// Example: `body, meta, err := VerifyAndRead("abc123", r)`.
+func VerifyAndRead(key string, r *http.Request, opts ...Option) ([]byte, WebhookMeta, error) {
+	cfg := newConfig(opts)
+	buf := new(bytes.Buffer)
+	if _, _, err := verifyHTTP(staticKeys(key), cfg, buf, discardResponseWriter{}, r); err != nil {
+		return nil, WebhookMeta{}, err
+	}
+
+	return buf.Bytes(), webhookMeta(cfg, r), nil
+}
+
+// Get the details of the webhook from the request headers.
+func webhookMeta(cfg *config, r *http.Request) WebhookMeta {
+	return WebhookMeta{
+		Shop:       r.Header.Get(cfg.shopHeader),
+		Topic:      r.Header.Get("X-Shopify-Topic"),
+		APIVersion: r.Header.Get(apiVersionHeader),
+		WebhookID:  r.Header.Get(webhookIDHeader),
+	}
+}
@@ -0,0 +1,66 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// Test the body and details are returned for a verified webhook.
+func TestVerifyAndRead(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+	req.Header.Set("X-Shopify-API-Version", "2024-01")
+	req.Header.Set("X-Shopify-Webhook-Id", "b54557e4")
+
+	bb, meta, err := VerifyAndRead("secret", req)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	if string(bb) != body {
+		t.Errorf("expected body %q got %q", body, bb)
+	}
+
+	expected := WebhookMeta{Shop: "example.myshopify.com", Topic: "orders/create", APIVersion: "2024-01", WebhookID: "b54557e4"}
+	if meta != expected {
+		t.Errorf("expected meta %+v got %+v", expected, meta)
+	}
+}
+
+// Test missing headers leave their details empty.
+func TestVerifyAndReadMissingHeaders(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	_, meta, err := VerifyAndRead("secret", req)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	expected := WebhookMeta{Shop: "example.myshopify.com"}
+	if meta != expected {
+		t.Errorf("expected meta %+v got %+v", expected, meta)
+	}
+}
+
+// Test a failed verification returns the reason and no body.
+func TestVerifyAndReadInvalid(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+
+	bb, meta, err := VerifyAndRead("secret", req)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+
+	if bb != nil {
+		t.Errorf("expected no body got %q", bb)
+	}
+
+	if meta != (WebhookMeta{}) {
+		t.Errorf("expected empty meta got %+v", meta)
+	}
+}
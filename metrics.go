@@ -0,0 +1,73 @@
+package http_shopify_webhook
+
+import "errors"
+
+// Metrics records the outcome of verifications.
+// Implement it to back the counters with a metrics library, such as Prometheus.
+type Metrics interface {
+	// IncVerified counts a verified webhook for the topic.
+	IncVerified(topic string)
+
+	// IncRejected counts a rejected webhook for the reason.
+	IncRejected(reason string)
+
+	// ObserveBodySize records the number of body bytes read for a webhook, verified or not.
+	// Bodies over the max size are observed at the limit, as nothing past it is read.
+	// Not called for webhooks rejected on their headers or skipped, as no body was read.
+	ObserveBodySize(n int)
+}
+
+// Record metrics of verification outcomes.
+// Rejection reasons are the messages of the sentinel errors, such as `invalid webhook signature`,
+// or `other` for errors which are not one, such as from `WithBeforeVerify`.
+// Example: `WebhookVerify("abc123", handler, WithMetrics(promMetrics))`.
+func WithMetrics(m Metrics) Option {
+	return func(cfg *config) {
+		cfg.metrics = m
+	}
+}
+
+// Metrics which record nothing, used by default.
+type nopMetrics struct{}
+
+func (nopMetrics) IncVerified(topic string)  {}
+func (nopMetrics) IncRejected(reason string) {}
+func (nopMetrics) ObserveBodySize(n int)     {}
+
+// Reasons rejections are counted for, in the order they are checked.
+var rejectReasons = []error{
+	ErrReadTimeout,
+	ErrMethodNotAllowed,
+	ErrUnsupportedMediaType,
+	ErrMissingShop,
+	ErrMissingHMAC,
+	ErrAmbiguousHMAC,
+	ErrMissingHeader,
+	ErrHeaderTooLong,
+	ErrInvalidShopDomain,
+	ErrInvalidSignature,
+	ErrUnknownShop,
+	ErrShopNotAllowed,
+	ErrRateLimited,
+	ErrBodyTooLarge,
+	ErrBodyRead,
+	ErrEmptyBody,
+	ErrMissingTimestamp,
+	ErrInvalidTimestamp,
+	ErrExpired,
+}
+
+// Reason for rejections which are not from a sentinel error, such as from `WithBeforeVerify`.
+const otherRejectReason = "other"
+
+// Get the reason for a rejection, the message of its sentinel error.
+// Other errors share one reason, so the reasons stay a small fixed set.
+func rejectReason(err error) string {
+	for _, reason := range rejectReasons {
+		if errors.Is(err, reason) {
+			return reason.Error()
+		}
+	}
+
+	return otherRejectReason
+}
@@ -0,0 +1,204 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Metrics which record the calls made.
+type fakeMetrics struct {
+	verified []string
+	rejected []string
+	sizes    []int
+}
+
+func (m *fakeMetrics) IncVerified(topic string) {
+	m.verified = append(m.verified, topic)
+}
+
+func (m *fakeMetrics) IncRejected(reason string) {
+	m.rejected = append(m.rejected, reason)
+}
+
+func (m *fakeMetrics) ObserveBodySize(n int) {
+	m.sizes = append(m.sizes, n)
+}
+
+// Test a verified webhook is counted for its topic.
+func TestWithMetricsVerified(t *testing.T) {
+	m := &fakeMetrics{}
+	body := `{"key":"value"}`
+	req := newTopicRequest("secret", "orders/create")
+
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMetrics(m)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(m.verified) != 1 || m.verified[0] != "orders/create" {
+		t.Errorf("expected verified webhook for topic %q got %v", "orders/create", m.verified)
+	}
+
+	if len(m.rejected) != 0 {
+		t.Errorf("expected no rejected webhooks got %v", m.rejected)
+	}
+
+	if len(m.sizes) != 1 || m.sizes[0] != len(body) {
+		t.Errorf("expected body size %v to be observed got %v", len(body), m.sizes)
+	}
+}
+
+// Test rejected webhooks are counted for their reason.
+func TestWithMetricsRejected(t *testing.T) {
+	body := `{"key":"value"}`
+	tests := []struct {
+		name   string
+		req    *http.Request
+		reason string
+		opts   []Option
+	}{
+		{
+			name:   "invalid signature",
+			req:    newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)),
+			reason: ErrInvalidSignature.Error(),
+		},
+		{
+			name:   "body read",
+			req:    newWebhookRequest(&errReader{}, "example.myshopify.com", sign("secret", body)),
+			reason: ErrBodyRead.Error(),
+		},
+		{
+			name:   "cancelled",
+			req:    cancelledRequest(t),
+			reason: ErrReadTimeout.Error(),
+			opts:   []Option{WithReadTimeout(time.Minute)},
+		},
+		{
+			name:   "before verify",
+			req:    newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)),
+			reason: "other",
+			opts: []Option{WithBeforeVerify(func(r *http.Request) error {
+				return errors.New("request 9f86d081 rejected")
+			})},
+		},
+	}
+
+	for _, tt := range tests {
+		m := &fakeMetrics{}
+		WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, append(tt.opts, WithMetrics(m))...).ServeHTTP(httptest.NewRecorder(), tt.req)
+
+		if len(m.rejected) != 1 || m.rejected[0] != tt.reason {
+			t.Errorf("%s: expected rejection for reason %q got %v", tt.name, tt.reason, m.rejected)
+		}
+
+		if len(m.verified) != 0 {
+			t.Errorf("%s: expected no verified webhooks got %v", tt.name, m.verified)
+		}
+	}
+}
+
+// Test the observed body size matches the payload for verified and rejected webhooks.
+func TestWithMetricsBodySize(t *testing.T) {
+	for _, size := range []int{0, 1, 512, 4096, 64 * 1024} {
+		body := strings.Repeat("a", size)
+		for _, key := range []string{"secret", "other"} {
+			m := &fakeMetrics{}
+			req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign(key, body))
+			WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMetrics(m)).ServeHTTP(httptest.NewRecorder(), req)
+
+			if len(m.sizes) != 1 || m.sizes[0] != size {
+				t.Errorf("expected body size %v to be observed for key %q got %v", size, key, m.sizes)
+			}
+		}
+	}
+}
+
+// Sets up a signed request with a body which never finishes, already cancelled.
+func cancelledRequest(t *testing.T) *http.Request {
+	req, body := newBlockingRequest()
+	t.Cleanup(func() {
+		body.Close()
+	})
+
+	ctx, cancel := context.WithCancel(req.Context())
+	cancel()
+
+	return req.WithContext(ctx)
+}
+
+// Test the observed body size is the limit when the max body size cuts the read short.
+func TestWithMetricsBodySizeTruncated(t *testing.T) {
+	body := strings.Repeat("a", 100)
+	for _, bodyCopy := range []bool{true, false} {
+		for _, timeout := range []time.Duration{0, time.Minute} {
+			m := &fakeMetrics{}
+			req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+			rec := httptest.NewRecorder()
+			WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMetrics(m), WithMaxBodySize(10), WithBodyCopy(bodyCopy), WithReadTimeout(timeout)).ServeHTTP(rec, req)
+
+			if c := rec.Code; c != http.StatusRequestEntityTooLarge {
+				t.Errorf("expected status code %v got %v", http.StatusRequestEntityTooLarge, c)
+			}
+
+			if len(m.sizes) != 1 || m.sizes[0] != 10 {
+				t.Errorf("expected body size %v to be observed with body copy %v and timeout %v got %v", 10, bodyCopy, timeout, m.sizes)
+			}
+		}
+	}
+}
+
+// Test the observed body size is what was read before a read timeout.
+func TestWithMetricsBodySizeTimeout(t *testing.T) {
+	m := &fakeMetrics{}
+	body := newBlockingBody()
+	defer body.Close()
+
+	req := newWebhookRequest(nil, "example.myshopify.com", sign("secret", ""))
+	req.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(strings.NewReader(`{"key"`), body), body}
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMetrics(m), WithReadTimeout(10*time.Millisecond)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(m.sizes) != 1 || m.sizes[0] != len(`{"key"`) {
+		t.Errorf("expected body size %v to be observed got %v", len(`{"key"`), m.sizes)
+	}
+}
+
+// Test no body size is observed for webhooks rejected before the body is read.
+func TestWithMetricsBodySizeNotRead(t *testing.T) {
+	body := `{"key":"value"}`
+	reqs := map[string]*http.Request{
+		"missing HMAC": newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", ""),
+		"missing shop": newWebhookRequest(bytes.NewBufferString(body), "", sign("secret", body)),
+		"method":       httptest.NewRequest(http.MethodGet, "/webhook", nil),
+	}
+
+	for name, req := range reqs {
+		m := &fakeMetrics{}
+		WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMetrics(m)).ServeHTTP(httptest.NewRecorder(), req)
+
+		if len(m.sizes) != 0 {
+			t.Errorf("%s: expected no body size to be observed got %v", name, m.sizes)
+		}
+		if len(m.rejected) != 1 {
+			t.Errorf("%s: expected the rejection to be counted got %v", name, m.rejected)
+		}
+	}
+}
+
+// Test the observed body size is counted without the body copy.
+func TestWithMetricsBodySizeStream(t *testing.T) {
+	m := &fakeMetrics{}
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithMetrics(m), WithBodyCopy(false)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if len(m.sizes) != 1 || m.sizes[0] != len(body) {
+		t.Errorf("expected body size %v to be observed got %v", len(body), m.sizes)
+	}
+}
@@ -0,0 +1,20 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Middleware wrapping the next handler, such as with the verification.
+type Middleware func(http.HandlerFunc) http.HandlerFunc
+
+// Compose the middleware into one, applied in order: the first is the outermost,
+// running first, and the handler runs last.
+// `Chain(a, b)(h)` is the same as `a(b(h))`.
+// Example: `Chain(logRequests, verify, dedup)(handler)`.
+func Chain(mws ...Middleware) Middleware {
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		for i := len(mws) - 1; i >= 0; i-- {
+			fn = mws[i](fn)
+		}
+
+		return fn
+	}
+}
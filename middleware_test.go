@@ -0,0 +1,71 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Middleware which records its name before and after the next handler.
+func recordMiddleware(name string, calls *[]string) Middleware {
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			*calls = append(*calls, name+" before")
+			fn(w, r)
+			*calls = append(*calls, name+" after")
+		}
+	}
+}
+
+// Test the middleware are applied in order around the handler.
+func TestChain(t *testing.T) {
+	var calls []string
+	h := Chain(recordMiddleware("a", &calls), recordMiddleware("b", &calls))(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+	h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	exp := []string{"a before", "b before", "handler", "b after", "a after"}
+	if len(calls) != len(exp) {
+		t.Fatalf("expected calls %v got %v", exp, calls)
+	}
+
+	for i := range exp {
+		if calls[i] != exp[i] {
+			t.Errorf("expected call %v to be %q got %q", i, exp[i], calls[i])
+		}
+	}
+}
+
+// Test an empty chain runs the handler as is.
+func TestChainEmpty(t *testing.T) {
+	ran := false
+	Chain()(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodPost, "/", nil))
+
+	if !ran {
+		t.Errorf("expected handler to run but did not")
+	}
+}
+
+// Test the verifier composes with other middleware.
+func TestChainVerify(t *testing.T) {
+	verify, err := New(Config{Secret: "secret"})
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+
+	var calls []string
+	h := Chain(recordMiddleware("log", &calls), verify)(func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+	})
+
+	body := `{"key":"value"}`
+	h.ServeHTTP(httptest.NewRecorder(), newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)))
+
+	if len(calls) != 2 || calls[0] != "log before" || calls[1] != "log after" {
+		t.Errorf("expected the verifier to stop the chain got %v", calls)
+	}
+}
@@ -0,0 +1,34 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+)
+
+// Retry a failed verification against the body without a single trailing newline,
+// such as one appended by a reverse proxy. Only one `\n` is stripped, not other whitespace.
+// When it matches, the next handler gets the body without the newline.
+// This weakens the check, so it is off by default, and only applies with the body copy.
+// Example: `WebhookVerify("abc123", handler, WithTrailingNewlineTolerance())`.
+func WithTrailingNewlineTolerance() Option {
+	return func(cfg *config) {
+		cfg.trailingNewline = true
+	}
+}
+
+// Verify the body in the buffer again without its trailing newline, after the failure.
+// On success, the newline is dropped from the buffer as well.
+func verifyTrailingNewline(cfg *config, buf *bytes.Buffer, keys []string, shop string, shmacs []string, err error) error {
+	bb := buf.Bytes()
+	if !errors.Is(err, ErrInvalidSignature) || !bytes.HasSuffix(bb, []byte("\n")) {
+		// Nothing to strip, or failed for another reason.
+		return err
+	}
+
+	if verifyRequestHMACs(cfg, keys, shop, shmacs, bb[:len(bb)-1]) != nil {
+		return err
+	}
+	buf.Truncate(len(bb) - 1)
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Runs the body, signed as the signed body, through the verifier with the options.
+// Returns the response and the body the next handler got.
+func serveNewline(body string, signed string, opts ...Option) (*httptest.ResponseRecorder, string) {
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", signed))
+
+	var got string
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		bb, _ := io.ReadAll(r.Body)
+		got = string(bb)
+	}, opts...).ServeHTTP(rec, req)
+
+	return rec, got
+}
+
+// Test a body with an appended newline verifies with the option, without the newline.
+func TestWithTrailingNewlineTolerance(t *testing.T) {
+	body := `{"key":"value"}`
+	rec, got := serveNewline(body+"\n", body, WithTrailingNewlineTolerance())
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if got != body {
+		t.Errorf("expected body %q got %q", body, got)
+	}
+}
+
+// Test a body without an appended newline still verifies with the option.
+func TestWithTrailingNewlineToleranceUnchanged(t *testing.T) {
+	for _, body := range []string{`{"key":"value"}`, "{\"key\":\"value\"}\n"} {
+		rec, got := serveNewline(body, body, WithTrailingNewlineTolerance())
+
+		if c := rec.Code; c != http.StatusOK {
+			t.Errorf("expected status code %v got %v", http.StatusOK, c)
+		}
+
+		if got != body {
+			t.Errorf("expected body %q got %q", body, got)
+		}
+	}
+}
+
+// Test a body with an appended newline is rejected without the option.
+func TestWithTrailingNewlineToleranceDisabled(t *testing.T) {
+	body := `{"key":"value"}`
+	rec, _ := serveNewline(body+"\n", body)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test only a single newline is stripped, not other whitespace.
+func TestWithTrailingNewlineToleranceSingle(t *testing.T) {
+	body := `{"key":"value"}`
+	for _, suffix := range []string{"\n\n", " ", "\r\n", "\t"} {
+		rec, _ := serveNewline(body+suffix, body, WithTrailingNewlineTolerance())
+
+		if c := rec.Code; c != http.StatusBadRequest {
+			t.Errorf("expected status code %v for suffix %q got %v", http.StatusBadRequest, suffix, c)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// OAuth verify function wrapper, for the install and callback redirects from Shopify.
+// The redirect is signed through the `hmac` query parameter,
+// using the same secret key for the Shopify app.
+// Example: `OAuthVerify("abc123", callbackHandler)`.
+func OAuthVerify(key string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok := verifyOAuth(key, r.URL.Query()); !ok {
+			http.Error(w, "Invalid OAuth signature", http.StatusBadRequest)
+			return
+		}
+
+		fn(w, r)
+	}
+}
+
+// Verify the query parameters of an OAuth redirect.
+// Parameters other than the HMAC and signature are sorted and joined by `&`.
+// The HMAC is the hex encoded HMAC of the result.
+func verifyOAuth(key string, q url.Values) bool {
+	return verifyQuery(key, q.Get("hmac"), queryMessage(q, "&", "hmac", "signature"))
+}
@@ -0,0 +1,61 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the OAuth redirect against the example from Shopify's documentation.
+func TestOAuthVerify(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{
+			name:  "signed",
+			query: "code=0907a61c0c8d55e99db179b68161bc00&hmac=700e2dadb827fcc8609e9d5ce208b2e9cdaab9df07390d2cbca10d7c328fc4bf&shop=some-shop.myshopify.com&state=0.6784241404160823&timestamp=1337178173",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "signed with signature parameter",
+			query: "code=0907a61c0c8d55e99db179b68161bc00&hmac=700e2dadb827fcc8609e9d5ce208b2e9cdaab9df07390d2cbca10d7c328fc4bf&shop=some-shop.myshopify.com&signature=abc&state=0.6784241404160823&timestamp=1337178173",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "reordered",
+			query: "timestamp=1337178173&state=0.6784241404160823&shop=some-shop.myshopify.com&hmac=700e2dadb827fcc8609e9d5ce208b2e9cdaab9df07390d2cbca10d7c328fc4bf&code=0907a61c0c8d55e99db179b68161bc00",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "tampered",
+			query: "code=0907a61c0c8d55e99db179b68161bc00&hmac=700e2dadb827fcc8609e9d5ce208b2e9cdaab9df07390d2cbca10d7c328fc4bf&shop=other-shop.myshopify.com&state=0.6784241404160823&timestamp=1337178173",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "unsigned",
+			query: "code=0907a61c0c8d55e99db179b68161bc00&shop=some-shop.myshopify.com&state=0.6784241404160823&timestamp=1337178173",
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		ran := false
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+		})
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/auth/callback?"+tt.query, nil)
+		OAuthVerify("hush", nh).ServeHTTP(rec, req)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+
+		if ran != (tt.code == http.StatusOK) {
+			t.Errorf("%s: expected next handler to run only when signed", tt.name)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"crypto/sha256"
+	"hash"
+	"log/slog"
+	"mime"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Default maximum size of a webhook body, matching Shopify's payload ceiling.
+const DefaultMaxBodySize int64 = 10 << 20
+
+// Default headers holding the HMAC and the shop domain.
+const (
+	DefaultHMACHeader = "X-Shopify-Hmac-Sha256"
+	DefaultShopHeader = "X-Shopify-Shop-Domain"
+)
+
+// Configuration for the verifier, built from the options.
+type config struct {
+	// Skips verification of matching requests, nil to verify all.
+	skip func(r *http.Request) bool
+
+	// Called before each request is verified, nil for none.
+	beforeVerify func(r *http.Request) error
+
+	// Run the next handler for failed verifications, instead of rejecting them,
+	// and queue them as well when insecure.
+	passThrough   bool
+	allowInsecure bool
+
+	// Topics verification is enforced for, empty for all.
+	enforceTopics map[string]bool
+
+	// Headers holding the HMAC and the shop domain.
+	hmacHeader string
+	shopHeader string
+
+	// Hash the HMAC is created with.
+	hashFunc func() hash.Hash
+
+	// Try each differing value of the HMAC header, instead of rejecting them.
+	tryAllHMACs bool
+
+	// Decodes the HMAC header to the raw digest of the size.
+	decodeHMAC func(shmac string, size int) ([]byte, error)
+
+	// Pattern shop domains must match, nil for any.
+	shopPattern *regexp.Regexp
+
+	// Shops webhooks are accepted from, empty for any.
+	allowedShops map[string]bool
+
+	// Maximum length of the Shopify headers, zero or less for no limit.
+	maxHeaderLength int
+
+	// Headers verified webhooks must have.
+	requiredHeaders []string
+
+	// Header trusted for the client IP behind a proxy, empty for none.
+	proxyHeader string
+
+	// HTTP methods webhooks can be sent with.
+	allowedMethods []string
+
+	// Media types webhooks can be sent as, empty for any.
+	contentTypes []string
+
+	// Maximum number of bytes read from the body, zero or less for no limit.
+	maxBodySize int64
+
+	// Reject empty bodies.
+	requireBody bool
+
+	// Retry failed verifications without a trailing newline on the body.
+	trailingNewline bool
+
+	// Keep the body and restore it for the next handler.
+	bodyCopy bool
+
+	// Decompress gzip encoded bodies.
+	decompress bool
+
+	// Maximum time taken to read the body, zero or less for no limit.
+	readTimeout time.Duration
+
+	// Writes the response when verification fails.
+	errorHandler func(w http.ResponseWriter, r *http.Request, err error)
+
+	// Status written by the default error handler for signature mismatches.
+	signatureStatus int
+
+	// Body and its content type written by the default error handler, nil for plain text.
+	responseType string
+	responseBody []byte
+
+	// Maximum age of the webhook and the header holding its timestamp.
+	maxAge          time.Duration
+	timestampHeader string
+
+	// Limits the rate of webhooks per shop, nil for no limit.
+	rateLimiter ShopRateLimiter
+
+	// Store of delivered webhook IDs.
+	dedup    DedupStore
+	dedupTTL time.Duration
+
+	// Set response headers with the verification result, never in production.
+	debugHeaders bool
+
+	// Writer verified bodies are copied to, nil for none.
+	tee *bodyTee
+
+	// Logger for verification failures, nil for none.
+	logger *slog.Logger
+
+	// Metrics of verification outcomes.
+	metrics Metrics
+
+	// Hook started around the verification, such as for tracing.
+	traceHook func(r *http.Request) (context.Context, func(err error))
+
+	// Called for every verified webhook, nil for none.
+	onSuccess func(r *http.Request)
+
+	// Processes verified webhooks in the background, instead of the next handler.
+	async func(shop, topic string, body []byte)
+
+	// Recovers from panics in the next handler, nil to not recover.
+	recover func(w http.ResponseWriter, r *http.Request, recovered any)
+
+	// Current time, for time based checks, and the clock set to replace it.
+	now   func() time.Time
+	clock func() time.Time
+}
+
+// Option configures the verifier.
+// Options are passed as the last arguments of the verify functions,
+// calling them without any options, such as `WebhookVerify("abc123", handler)`,
+// behaves identically to before options were introduced.
+// Options are applied in order, the last one for a setting wins.
+type Option func(*config)
+
+// Build the config from the defaults and the passed options.
+func newConfig(opts []Option) *config {
+	cfg := &config{
+		hmacHeader:      DefaultHMACHeader,
+		shopHeader:      DefaultShopHeader,
+		hashFunc:        sha256.New,
+		decodeHMAC:      decodeHMAC,
+		allowedMethods:  []string{http.MethodPost},
+		maxBodySize:     DefaultMaxBodySize,
+		bodyCopy:        true,
+		signatureStatus: http.StatusBadRequest,
+		timestampHeader: DefaultTimestampHeader,
+		metrics:         nopMetrics{},
+		now:             time.Now,
+	}
+	for _, opt := range opts {
+		opt(cfg)
+	}
+	applyClock(cfg)
+	if cfg.errorHandler == nil {
+		cfg.errorHandler = defaultErrorHandler(cfg)
+	}
+
+	return cfg
+}
+
+// Limit the size of the webhook body which will be read.
+// Bodies over the limit are rejected with a 413, pass zero to disable the limit.
+// Example: `WebhookVerify("abc123", handler, WithMaxBodySize(1 << 20))`.
+func WithMaxBodySize(n int64) Option {
+	return func(cfg *config) {
+		cfg.maxBodySize = n
+	}
+}
+
+// Handle verification failures with a custom handler.
+// The handler receives the reason for the failure and is responsible for the response.
+// Without this option, a plain text error is written, mostly as a 400.
+// Example: `WebhookVerify("abc123", handler, WithErrorHandler(logAndReject))`.
+func WithErrorHandler(fn func(w http.ResponseWriter, r *http.Request, err error)) Option {
+	return func(cfg *config) {
+		cfg.errorHandler = fn
+	}
+}
+
+// Set the status written for a signature mismatch, such as a 401, defaults to a 400.
+// Malformed requests, such as an unreadable body, keep their own status.
+// Has no effect with a custom error handler.
+// Example: `WebhookVerify("abc123", handler, WithUnauthorizedStatus(http.StatusUnauthorized))`.
+func WithUnauthorizedStatus(code int) Option {
+	return func(cfg *config) {
+		cfg.signatureStatus = code
+	}
+}
+
+// Write the body with the content type for every failure, instead of the plain text error,
+// such as a fixed JSON error. The status still matches the reason, or `WithUnauthorizedStatus`.
+// Has no effect with a custom error handler.
+// Example: `WebhookVerify("abc123", handler, WithResponseBody("application/json", errJSON))`.
+func WithResponseBody(contentType string, body []byte) Option {
+	return func(cfg *config) {
+		cfg.responseType = contentType
+		cfg.responseBody = body
+	}
+}
+
+// Read the HMAC from a different header, such as one rewritten by a proxy.
+// Header lookups remain case-insensitive.
+// Example: `WebhookVerify("abc123", handler, WithHMACHeader("X-Proxy-Hmac"))`.
+func WithHMACHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.hmacHeader = name
+	}
+}
+
+// Read the shop domain from a different header, such as one rewritten by a proxy.
+// Header lookups remain case-insensitive.
+// Example: `WebhookVerify("abc123", handler, WithShopHeader("X-Proxy-Shop"))`.
+func WithShopHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.shopHeader = name
+	}
+}
+
+// Create the HMAC with a different hash than SHA256, such as `sha512.New`.
+// Useful for verifying webhooks from other sources signed the same way,
+// usually along with `WithHMACHeader` for the header they send the HMAC in.
+// Example: `WebhookVerify("abc123", handler, WithHashFunc(sha512.New))`.
+func WithHashFunc(fn func() hash.Hash) Option {
+	return func(cfg *config) {
+		cfg.hashFunc = fn
+	}
+}
+
+// Decode the HMAC header to the raw digest with the function, instead of as standard base64.
+// Useful behind intermediaries which re-encode the header, such as to URL-safe base64 or hex.
+// The decoded bytes are still compared to the digest in constant time.
+// Example: `WebhookVerify("abc123", handler, WithHMACDecoder(hex.DecodeString))`.
+func WithHMACDecoder(fn func(s string) ([]byte, error)) Option {
+	return func(cfg *config) {
+		cfg.decodeHMAC = func(shmac string, size int) ([]byte, error) {
+			return fn(shmac)
+		}
+	}
+}
+
+// Set the HTTP methods webhooks can be sent with, defaults to only POST as Shopify uses.
+// Other methods are rejected with a 405 before any verification.
+// Example: `WebhookVerify("abc123", handler, WithAllowedMethods(http.MethodPost, http.MethodPut))`.
+func WithAllowedMethods(methods ...string) Option {
+	return func(cfg *config) {
+		cfg.allowedMethods = methods
+	}
+}
+
+// Check the request method is allowed, setting the `Allow` header if not.
+func checkMethod(cfg *config, w http.ResponseWriter, r *http.Request) error {
+	for _, m := range cfg.allowedMethods {
+		if r.Method == m {
+			return nil
+		}
+	}
+	w.Header().Set("Allow", strings.Join(cfg.allowedMethods, ", "))
+
+	return ErrMethodNotAllowed
+}
+
+// Set the media types webhooks can be sent as, such as `application/json`.
+// Parameters such as the charset are ignored, other types are rejected with a 415
+// before any verification. Without this option, any content type is accepted.
+// Example: `WebhookVerify("abc123", handler, WithContentType("application/json"))`.
+func WithContentType(allowed ...string) Option {
+	return func(cfg *config) {
+		cfg.contentTypes = allowed
+	}
+}
+
+// Check the request content type is allowed, if any were set.
+func checkContentType(cfg *config, r *http.Request) error {
+	if len(cfg.contentTypes) == 0 {
+		return nil
+	}
+
+	// A missing or malformed content type matches nothing.
+	mt, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if err != nil {
+		return ErrUnsupportedMediaType
+	}
+	for _, ct := range cfg.contentTypes {
+		if strings.EqualFold(mt, ct) {
+			return nil
+		}
+	}
+
+	return ErrUnsupportedMediaType
+}
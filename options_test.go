@@ -0,0 +1,421 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Sign the body with the key the same way Shopify does.
+func sign(key string, body string) string {
+	h := hmac.New(sha256.New, []byte(key))
+	h.Write([]byte(body))
+
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Sets up a signed request and runs it through the verifier with the options.
+func serveWithOptions(body string, opts ...Option) (*httptest.ResponseRecorder, bool) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", sign("secret", body))
+
+	ran := false
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	})
+	WebhookVerify("secret", nh, opts...).ServeHTTP(rec, req)
+
+	return rec, ran
+}
+
+// Test the default max body size.
+func TestDefaultMaxBodySize(t *testing.T) {
+	if s := newConfig(nil).maxBodySize; s != DefaultMaxBodySize {
+		t.Errorf("expected default max body size of %v got %v", DefaultMaxBodySize, s)
+	}
+}
+
+// Test options are applied in order onto the defaults.
+func TestNewConfigOptions(t *testing.T) {
+	cfg := newConfig([]Option{WithMaxBodySize(10), WithMaxBodySize(20)})
+	if s := cfg.maxBodySize; s != 20 {
+		t.Errorf("expected the last option to win with %v got %v", 20, s)
+	}
+
+	cfg = newConfig([]Option{WithMaxBodySize(0)})
+	if s := cfg.maxBodySize; s != 0 {
+		t.Errorf("expected max body size to be disabled got %v", s)
+	}
+}
+
+// Test the zero-options call behaves as before.
+func TestZeroOptions(t *testing.T) {
+	rec, ran := serveWithOptions(`{"key":"value"}`)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Test a body just under the limit is verified.
+func TestMaxBodySizeUnder(t *testing.T) {
+	body := `{"key":"value"}`
+	rec, ran := serveWithOptions(body, WithMaxBodySize(int64(len(body))))
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Test a body just over the limit is rejected.
+func TestMaxBodySizeOver(t *testing.T) {
+	body := `{"key":"value"}`
+	rec, ran := serveWithOptions(body, WithMaxBodySize(int64(len(body)-1)))
+
+	if c := rec.Code; c != http.StatusRequestEntityTooLarge {
+		t.Errorf("expected status code %v got %v", http.StatusRequestEntityTooLarge, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
+
+// Test a custom error handler receives the reason for the failure.
+func TestWithErrorHandler(t *testing.T) {
+	tests := []struct {
+		name string
+		shop string
+		hmac string
+		err  error
+	}{
+		{name: "missing shop", shop: "", hmac: sign("secret", `{"key":"value"}`), err: ErrMissingShop},
+		{name: "missing hmac", shop: "example.myshopify.com", hmac: "", err: ErrMissingHMAC},
+		{name: "mismatch", shop: "example.myshopify.com", hmac: sign("other", `{"key":"value"}`), err: ErrInvalidSignature},
+	}
+
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(`{"key":"value"}`))
+		req.Header.Set("X-Shopify-Shop-Domain", tt.shop)
+		req.Header.Set("X-Shopify-Hmac-Sha256", tt.hmac)
+
+		var herr error
+		eh := func(w http.ResponseWriter, r *http.Request, err error) {
+			herr = err
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"error":"unauthorized"}`))
+		}
+
+		ran := false
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+		})
+
+		rec := httptest.NewRecorder()
+		WebhookVerify("secret", nh, WithErrorHandler(eh)).ServeHTTP(rec, req)
+
+		if !errors.Is(herr, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, herr)
+		}
+
+		if c := rec.Code; c != http.StatusUnauthorized {
+			t.Errorf("%s: expected status code %v got %v", tt.name, http.StatusUnauthorized, c)
+		}
+
+		if ran {
+			t.Errorf("%s: expected next handler to not run but it did", tt.name)
+		}
+	}
+}
+
+// Serves a signed request with the method through the verifier with the options.
+func serveMethod(method string, opts ...Option) *httptest.ResponseRecorder {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Method = method
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, opts...).ServeHTTP(rec, req)
+
+	return rec
+}
+
+// Test only POST is allowed by default.
+func TestAllowedMethodsDefault(t *testing.T) {
+	if c := serveMethod(http.MethodPost).Code; c != http.StatusOK {
+		t.Errorf("expected status code %v for POST got %v", http.StatusOK, c)
+	}
+
+	rec := serveMethod(http.MethodGet)
+	if c := rec.Code; c != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %v for GET got %v", http.StatusMethodNotAllowed, c)
+	}
+
+	if a := rec.Header().Get("Allow"); a != "POST" {
+		t.Errorf("expected Allow header %q got %q", "POST", a)
+	}
+}
+
+// Test a custom set of allowed methods.
+func TestWithAllowedMethods(t *testing.T) {
+	opt := WithAllowedMethods(http.MethodPost, http.MethodPut)
+
+	if c := serveMethod(http.MethodPut, opt).Code; c != http.StatusOK {
+		t.Errorf("expected status code %v for PUT got %v", http.StatusOK, c)
+	}
+
+	rec := serveMethod(http.MethodDelete, opt)
+	if c := rec.Code; c != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %v for DELETE got %v", http.StatusMethodNotAllowed, c)
+	}
+
+	if a := rec.Header().Get("Allow"); a != "POST, PUT" {
+		t.Errorf("expected Allow header %q got %q", "POST, PUT", a)
+	}
+}
+
+// Test the HMAC and shop can be read from other headers.
+func TestWithHeaderNames(t *testing.T) {
+	body := `{"key":"value"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("x-proxy-shop", "example.myshopify.com")
+	req.Header.Set("x-proxy-hmac", sign("secret", body))
+
+	ran := false
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	})
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", nh, WithHMACHeader("X-Proxy-Hmac"), WithShopHeader("X-Proxy-Shop")).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK || !ran {
+		t.Errorf("expected status code %v and next handler to run got %v", http.StatusOK, c)
+	}
+
+	// The default headers are no longer used.
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	if err := serveForError(req, WithHMACHeader("X-Proxy-Hmac")); !errors.Is(err, ErrMissingHMAC) {
+		t.Errorf("expected error %v got %v", ErrMissingHMAC, err)
+	}
+}
+
+// Test the status for a signature mismatch can be changed without affecting others.
+func TestWithUnauthorizedStatus(t *testing.T) {
+	body := `{"key":"value"}`
+	opt := WithUnauthorizedStatus(http.StatusUnauthorized)
+	nh := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		opts []Option
+		code int
+	}{
+		{name: "mismatch default", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)), code: http.StatusBadRequest},
+		{name: "mismatch", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)), opts: []Option{opt}, code: http.StatusUnauthorized},
+		{name: "body read", req: newWebhookRequest(&errReader{}, "example.myshopify.com", sign("secret", body)), opts: []Option{opt}, code: http.StatusBadRequest},
+		{name: "missing shop", req: newWebhookRequest(bytes.NewBufferString(body), "", sign("secret", body)), opts: []Option{opt}, code: http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		WebhookVerify("secret", nh, tt.opts...).ServeHTTP(rec, tt.req)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+	}
+}
+
+// Test the custom response body and content type are written on failure, with the reason's status.
+func TestWithResponseBody(t *testing.T) {
+	body := `{"key":"value"}`
+	errJSON := `{"error":"invalid webhook"}`
+	opt := WithResponseBody("application/json", []byte(errJSON))
+	nh := func(w http.ResponseWriter, r *http.Request) {}
+
+	tests := []struct {
+		name string
+		req  *http.Request
+		opts []Option
+		code int
+	}{
+		{name: "mismatch", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)), opts: []Option{opt}, code: http.StatusBadRequest},
+		{name: "mismatch status", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)), opts: []Option{opt, WithUnauthorizedStatus(http.StatusUnauthorized)}, code: http.StatusUnauthorized},
+		{name: "too large", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)), opts: []Option{opt, WithMaxBodySize(4)}, code: http.StatusRequestEntityTooLarge},
+	}
+
+	for _, tt := range tests {
+		rec := httptest.NewRecorder()
+		WebhookVerify("secret", nh, tt.opts...).ServeHTTP(rec, tt.req)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+
+		if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+			t.Errorf("%s: expected content type %q got %q", tt.name, "application/json", ct)
+		}
+
+		if b := rec.Body.String(); b != errJSON {
+			t.Errorf("%s: expected body %q got %q", tt.name, errJSON, b)
+		}
+	}
+}
+
+// Test a verified webhook is passed on without the custom response body.
+func TestWithResponseBodyVerified(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("Ok"))
+	}, WithResponseBody("application/json", []byte(`{"error":"invalid webhook"}`))).ServeHTTP(rec, req)
+
+	if b := rec.Body.String(); b != "Ok" {
+		t.Errorf("expected body %q got %q", "Ok", b)
+	}
+}
+
+// Sets up a signed request with the content type and runs it through the verifier.
+func serveContentType(ct string, opts ...Option) *httptest.ResponseRecorder {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	if ct != "" {
+		req.Header.Set("Content-Type", ct)
+	}
+
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, opts...).ServeHTTP(rec, req)
+
+	return rec
+}
+
+// Test any content type is accepted by default.
+func TestContentTypeDefault(t *testing.T) {
+	for _, ct := range []string{"", "application/json", "text/plain"} {
+		if c := serveContentType(ct).Code; c != http.StatusOK {
+			t.Errorf("expected status code %v for %q got %v", http.StatusOK, ct, c)
+		}
+	}
+}
+
+// Test only the configured content types are accepted.
+func TestWithContentType(t *testing.T) {
+	opt := WithContentType("application/json", "application/xml")
+
+	for _, ct := range []string{"application/json", "application/xml", "Application/JSON"} {
+		if c := serveContentType(ct, opt).Code; c != http.StatusOK {
+			t.Errorf("expected status code %v for %q got %v", http.StatusOK, ct, c)
+		}
+	}
+
+	for _, ct := range []string{"", "text/plain", "application/jsonx", ";;"} {
+		if c := serveContentType(ct, opt).Code; c != http.StatusUnsupportedMediaType {
+			t.Errorf("expected status code %v for %q got %v", http.StatusUnsupportedMediaType, ct, c)
+		}
+	}
+}
+
+// Test parameters of the content type are ignored.
+func TestWithContentTypeParameters(t *testing.T) {
+	opt := WithContentType("application/json")
+
+	if c := serveContentType("application/json; charset=utf-8", opt).Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if c := serveContentType("text/plain; charset=utf-8", opt).Code; c != http.StatusUnsupportedMediaType {
+		t.Errorf("expected status code %v got %v", http.StatusUnsupportedMediaType, c)
+	}
+}
+
+// Test a body signed with SHA512 is verified with the hash option.
+func TestWithHashFunc(t *testing.T) {
+	body := `{"key":"value"}`
+	h := hmac.New(sha512.New, []byte("secret"))
+	h.Write([]byte(body))
+	shmac := base64.StdEncoding.EncodeToString(h.Sum(nil))
+
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", shmac)
+	if err := serveForError(req, WithHashFunc(sha512.New)); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	// Without the option, SHA256 is expected.
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", shmac)
+	if err := serveForError(req); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+
+	// With the option, SHA256 signatures no longer match.
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	if err := serveForError(req, WithHashFunc(sha512.New)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+}
+
+// Test an HMAC re-encoded as URL-safe base64 verifies with a matching decoder.
+func TestWithHMACDecoder(t *testing.T) {
+	// Find a body whose digest differs in the URL-safe encoding.
+	var body, shmac string
+	for i := 0; ; i++ {
+		body = fmt.Sprintf(`{"id":%d}`, i)
+		shmac = base64.URLEncoding.EncodeToString(digest("secret", []byte(body)))
+		if shmac != sign("secret", body) {
+			break
+		}
+	}
+
+	for _, bodyCopy := range []bool{true, false} {
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", shmac)
+		rec := httptest.NewRecorder()
+		WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithHMACDecoder(base64.URLEncoding.DecodeString), WithBodyCopy(bodyCopy)).ServeHTTP(rec, req)
+
+		if c := rec.Code; c != http.StatusOK {
+			t.Errorf("expected status code %v with body copy %v got %v", http.StatusOK, bodyCopy, c)
+		}
+	}
+
+	// Without the decoder, the header does not decode as standard base64.
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", shmac)
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test a decoded HMAC which does not match is rejected.
+func TestWithHMACDecoderMismatch(t *testing.T) {
+	body := `{"key":"value"}`
+	shmac := hex.EncodeToString(digest("other", []byte(body)))
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", shmac)
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithHMACDecoder(hex.DecodeString)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
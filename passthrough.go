@@ -0,0 +1,111 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// Run the next handler for webhooks which fail verification, instead of rejecting them.
+// The failure is still logged and counted, but no error response is written; the
+// handler must check `IsVerified` and decide what to do, such as in a gateway for
+// many providers. Unverified webhooks are never queued by `WithAsync`, so a handler
+// is still needed with it. A body which could not be fully read, such as one over
+// `WithMaxBodySize`, is passed on as far as it was read.
+// This is unsafe for handlers which trust the webhook, keep it off unless needed.
+// Example: `WebhookVerify("abc123", handler, WithPassThroughOnFailure())`.
+func WithPassThroughOnFailure() Option {
+	return func(cfg *config) {
+		cfg.passThrough = true
+	}
+}
+
+// Pass every webhook through to the next handler, verified or not, for monitoring
+// the verification before enforcing it, such as while onboarding an existing app.
+// Failures are still logged and counted, and the result is in the context through
+// `IsVerified` and `VerifyErrorFromContext`. Unlike `WithPassThroughOnFailure`,
+// unverified webhooks are queued by `WithAsync` as well. A body which could not be
+// fully read is passed on as far as it was read.
+// This is unsafe, only use it while confirming the secret is right.
+// Example: `WebhookVerify("abc123", handler, WithAllowInsecure())`.
+func WithAllowInsecure() Option {
+	return func(cfg *config) {
+		cfg.passThrough = true
+		cfg.allowInsecure = true
+	}
+}
+
+// Only enforce the verification for webhooks of the topics, such as `customers/redact`,
+// passing webhooks of other topics through to the next handler, and queue, verified or not.
+// As with `WithAllowInsecure`, the result is still logged, counted, and in the context.
+// Only failures of the signature and the headers are passed through, ones such as the
+// method, the body size, or the rate limit are rejected for every topic.
+// Webhooks without a topic are always enforced, and without this option, every topic is.
+// The topic is a header set by the sender, so anyone can reach the next handler
+// unverified by sending a topic which is not listed. Handle every webhook of a topic
+// not listed as untrusted, checking `IsVerified` before acting on it.
+// Example: `WebhookVerify("abc123", handler, WithEnforceTopics("customers/redact", "shop/redact"))`.
+func WithEnforceTopics(topics ...string) Option {
+	return func(cfg *config) {
+		cfg.enforceTopics = make(map[string]bool, len(topics))
+		for _, topic := range topics {
+			cfg.enforceTopics[topic] = true
+		}
+	}
+}
+
+// Check if verification is enforced for the topic of the request.
+// Webhooks without a topic are always enforced.
+func enforcedTopic(cfg *config, r *http.Request) bool {
+	topic := r.Header.Get("X-Shopify-Topic")
+	return len(cfg.enforceTopics) == 0 || topic == "" || cfg.enforceTopics[topic]
+}
+
+// Failures which webhooks of topics not enforced are passed through for, those of
+// the signature and the headers it covers. Failures of the request itself, such as
+// its method, body, or rate, are rejected for every topic.
+var passableFailures = []error{
+	ErrMissingShop,
+	ErrMissingHMAC,
+	ErrAmbiguousHMAC,
+	ErrMissingHeader,
+	ErrInvalidShopDomain,
+	ErrInvalidSignature,
+	ErrUnknownShop,
+	ErrShopNotAllowed,
+	ErrMissingTimestamp,
+	ErrInvalidTimestamp,
+	ErrExpired,
+}
+
+// Check if the failure can be passed through for a topic which is not enforced.
+func passableFailure(err error) bool {
+	for _, failure := range passableFailures {
+		if errors.Is(err, failure) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Check if the request can be queued by `WithAsync`.
+// Verified and skipped requests can, but failed ones only when not enforced.
+func queueable(cfg *config, r *http.Request) bool {
+	return !passedThrough(r) || cfg.allowInsecure || !enforcedTopic(cfg, r)
+}
+
+// Mark the request as not verified for the reason, for it to pass through to the next handler.
+func passThrough(r *http.Request, err error) *http.Request {
+	ctx := context.WithValue(r.Context(), verifiedKey, false)
+	ctx = context.WithValue(ctx, verifyErrorKey, err)
+
+	return r.WithContext(ctx)
+}
+
+// Check if the request failed verification and was passed through.
+// Skipped requests are not marked at all, so are not counted.
+func passedThrough(r *http.Request) bool {
+	verified, ok := r.Context().Value(verifiedKey).(bool)
+	return ok && !verified
+}
@@ -0,0 +1,305 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// Test failed webhooks run the next handler unverified when passed through.
+func TestWithPassThroughOnFailure(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	ran := false
+	verified := true
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		verified = IsVerified(r.Context())
+	}, WithPassThroughOnFailure()).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+
+	if verified {
+		t.Errorf("expected request to not be marked as verified")
+	}
+}
+
+// Test failed webhooks are rejected without passing through.
+func TestWithPassThroughOnFailureDisabled(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
+
+// Test verified webhooks are still marked as verified when passing through.
+func TestWithPassThroughOnFailureVerified(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	verified := false
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		verified = IsVerified(r.Context())
+	}, WithPassThroughOnFailure()).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !verified {
+		t.Errorf("expected request to be marked as verified")
+	}
+}
+
+// Test failed webhooks are not queued when passing through.
+func TestWithPassThroughOnFailureAsync(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	queued := make(chan struct{}, 1)
+	ran := false
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithPassThroughOnFailure(), WithAsync(func(shop string, topic string, body []byte) {
+		queued <- struct{}{}
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+
+	select {
+	case <-queued:
+		t.Errorf("expected webhook to not be queued")
+	default:
+	}
+}
+
+// Test every webhook passes through in insecure mode, with the result recorded accurately.
+func TestWithAllowInsecure(t *testing.T) {
+	body := `{"key":"value"}`
+	tests := []struct {
+		name     string
+		req      *http.Request
+		verified bool
+		err      error
+	}{
+		{name: "valid", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)), verified: true},
+		{name: "mismatch", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body)), err: ErrInvalidSignature},
+		{name: "missing HMAC", req: newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", ""), err: ErrMissingHMAC},
+	}
+
+	for _, tt := range tests {
+		m := &fakeMetrics{}
+		var logs bytes.Buffer
+		ran := false
+		var verified bool
+		var verr error
+		rec := httptest.NewRecorder()
+		WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+			verified = IsVerified(r.Context())
+			verr = VerifyErrorFromContext(r.Context())
+		}, WithAllowInsecure(), WithMetrics(m), WithLogger(slog.New(slog.NewTextHandler(&logs, nil)))).ServeHTTP(rec, tt.req)
+
+		if c := rec.Code; c != http.StatusOK || !ran {
+			t.Errorf("%s: expected the handler to run with status code %v got %v", tt.name, http.StatusOK, c)
+		}
+
+		if verified != tt.verified {
+			t.Errorf("%s: expected verified %v got %v", tt.name, tt.verified, verified)
+		}
+
+		if !errors.Is(verr, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, verr)
+		}
+
+		if tt.err == nil {
+			if len(m.verified) != 1 || len(m.rejected) != 0 || logs.Len() != 0 {
+				t.Errorf("%s: expected only a verified webhook recorded got %v, %v, and %q", tt.name, m.verified, m.rejected, logs.String())
+			}
+		} else {
+			if len(m.rejected) != 1 || m.rejected[0] != tt.err.Error() {
+				t.Errorf("%s: expected rejection for reason %q got %v", tt.name, tt.err, m.rejected)
+			}
+
+			if !strings.Contains(logs.String(), tt.err.Error()) {
+				t.Errorf("%s: expected failure to be logged got %q", tt.name, logs.String())
+			}
+		}
+	}
+}
+
+// Test unverified webhooks are queued in insecure mode.
+func TestWithAllowInsecureAsync(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	queued := make(chan string, 1)
+	WebhookVerify("secret", nil, WithAllowInsecure(), WithAsync(func(shop string, topic string, body []byte) {
+		queued <- string(body)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	select {
+	case b := <-queued:
+		if b != body {
+			t.Errorf("expected body %q to be queued got %q", body, b)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("expected webhook to be queued")
+	}
+}
+
+// Test the reason is recorded when passing through on failure.
+func TestWithPassThroughOnFailureError(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	var verr error
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		verr = VerifyErrorFromContext(r.Context())
+	}, WithPassThroughOnFailure()).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !errors.Is(verr, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, verr)
+	}
+}
+
+// Test a body which could not be fully read is passed through as far as it was read.
+func TestWithPassThroughOnFailureBodyTooLarge(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	var verr error
+	var bb []byte
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		verr = VerifyErrorFromContext(r.Context())
+		bb, _ = io.ReadAll(r.Body)
+	}, WithPassThroughOnFailure(), WithMaxBodySize(4)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if !errors.Is(verr, ErrBodyTooLarge) {
+		t.Errorf("expected error %v got %v", ErrBodyTooLarge, verr)
+	}
+
+	if len(bb) == 0 || !strings.HasPrefix(body, string(bb)) {
+		t.Errorf("expected the body read so far got %q", bb)
+	}
+}
+
+// Test options passing unverified requests through are ignored by WebhookVerifyRequest.
+func TestWebhookVerifyRequestPassThrough(t *testing.T) {
+	opts := map[string]Option{
+		"failure":  WithPassThroughOnFailure(),
+		"insecure": WithAllowInsecure(),
+		"enforce":  WithEnforceTopics("shop/redact"),
+		"skip":     WithSkipVerification(func(r *http.Request) bool { return true }),
+	}
+
+	for name, opt := range opts {
+		body := `{"key":"value"}`
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+		req.Header.Set("X-Shopify-Topic", "orders/create")
+
+		rec := httptest.NewRecorder()
+		if ok := WebhookVerifyRequest("secret", rec, req, opt); ok {
+			t.Errorf("%s: expected request to be rejected but was not", name)
+		}
+
+		if c := rec.Code; c != http.StatusBadRequest {
+			t.Errorf("%s: expected status code %v got %v", name, http.StatusBadRequest, c)
+		}
+	}
+}
+
+// Runs a request of the topic, signed with the key, through the verifier with the options.
+func serveTopic(topic string, key string, opts ...Option) (*httptest.ResponseRecorder, bool, error) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign(key, body))
+	req.Header.Set("X-Shopify-Topic", topic)
+
+	ran := false
+	var verr error
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		verr = VerifyErrorFromContext(r.Context())
+	}, opts...).ServeHTTP(rec, req)
+
+	return rec, ran, verr
+}
+
+// Test only the enforced topics are rejected for a bad signature.
+func TestWithEnforceTopics(t *testing.T) {
+	opt := WithEnforceTopics("customers/redact", "shop/redact")
+	tests := []struct {
+		name  string
+		topic string
+		key   string
+		opts  []Option
+		code  int
+		ran   bool
+		err   error
+	}{
+		{name: "enforced valid", topic: "customers/redact", key: "secret", opts: []Option{opt}, code: http.StatusOK, ran: true},
+		{name: "enforced invalid", topic: "customers/redact", key: "other", opts: []Option{opt}, code: http.StatusBadRequest},
+		{name: "not enforced valid", topic: "orders/create", key: "secret", opts: []Option{opt}, code: http.StatusOK, ran: true},
+		{name: "not enforced invalid", topic: "orders/create", key: "other", opts: []Option{opt}, code: http.StatusOK, ran: true, err: ErrInvalidSignature},
+		{name: "all enforced by default", topic: "orders/create", key: "other", code: http.StatusBadRequest},
+		{name: "not enforced too large", topic: "orders/create", key: "secret", opts: []Option{opt, WithMaxBodySize(4)}, code: http.StatusRequestEntityTooLarge},
+		{name: "not enforced rate limited", topic: "orders/create", key: "secret", opts: []Option{opt, WithRateLimit(denyLimiter{"example.myshopify.com": true})}, code: http.StatusTooManyRequests},
+	}
+
+	for _, tt := range tests {
+		rec, ran, verr := serveTopic(tt.topic, tt.key, tt.opts...)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+
+		if ran != tt.ran {
+			t.Errorf("%s: expected next handler to run %v got %v", tt.name, tt.ran, ran)
+		}
+
+		if !errors.Is(verr, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, verr)
+		}
+	}
+}
+
+// Test a webhook without a topic is enforced when topics are listed.
+func TestWithEnforceTopicsMissing(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithEnforceTopics("customers/redact")).ServeHTTP(rec, req)
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
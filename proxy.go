@@ -0,0 +1,65 @@
+package http_shopify_webhook
+
+import (
+	"crypto/hmac"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// App proxy verify function wrapper.
+// App proxy requests are signed through the `signature` query parameter
+// rather than a header, using the same secret key for the Shopify app.
+// Example: `AppProxyVerify("abc123", anotherHandler)`.
+func AppProxyVerify(key string, fn http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if ok := verifyAppProxy(key, r.URL.Query()); !ok {
+			http.Error(w, "Invalid app proxy signature", http.StatusBadRequest)
+			return
+		}
+
+		fn(w, r)
+	}
+}
+
+// Verify the query parameters of an app proxy request.
+// Parameters other than the signature are sorted and concatenated without a separator.
+// The signature is the hex encoded HMAC of the result.
+func verifyAppProxy(key string, q url.Values) bool {
+	return verifyQuery(key, q.Get("signature"), queryMessage(q, "", "signature"))
+}
+
+// Verify the hex encoded HMAC of the message built from query parameters.
+func verifyQuery(key string, sig string, msg string) bool {
+	dec, err := hex.DecodeString(sig)
+	if err != nil || len(dec) == 0 {
+		// Missing or not a valid hex string.
+		return false
+	}
+
+	return hmac.Equal(digest(key, []byte(msg)), dec)
+}
+
+// Build the message Shopify signs from the query parameters.
+// Each parameter is formatted as `key=value`, with multiple values joined by a comma,
+// then sorted and joined by the separator. Excluded parameters are left out.
+func queryMessage(q url.Values, sep string, exclude ...string) string {
+	params := make([]string, 0, len(q))
+	for k, v := range q {
+		skip := false
+		for _, e := range exclude {
+			if k == e {
+				skip = true
+				break
+			}
+		}
+		if !skip {
+			params = append(params, k+"="+strings.Join(v, ","))
+		}
+	}
+	sort.Strings(params)
+
+	return strings.Join(params, sep)
+}
@@ -0,0 +1,61 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the app proxy signature against the example from Shopify's documentation.
+func TestAppProxyVerify(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		code  int
+	}{
+		{
+			name:  "signed",
+			query: "extra=1&extra=2&shop=shop-name.myshopify.com&path_prefix=%2Fapps%2Fawesome_reviews&timestamp=1317327555&signature=a9718877bea71c2484f91608a7eaea1532bdf71f5c56825065fa4ccabe549ef3",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "signed with customer",
+			query: "extra=1&extra=2&shop=shop-name.myshopify.com&logged_in_customer_id=1&path_prefix=%2Fapps%2Fawesome_reviews&timestamp=1317327555&signature=4c68c8624d737112c91818c11017d24d334b524cb5c2b8ba08daa056f7395ddb",
+			code:  http.StatusOK,
+		},
+		{
+			name:  "tampered",
+			query: "extra=1&extra=2&shop=other-shop.myshopify.com&path_prefix=%2Fapps%2Fawesome_reviews&timestamp=1317327555&signature=a9718877bea71c2484f91608a7eaea1532bdf71f5c56825065fa4ccabe549ef3",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "unsigned",
+			query: "extra=1&extra=2&shop=shop-name.myshopify.com&path_prefix=%2Fapps%2Fawesome_reviews&timestamp=1317327555",
+			code:  http.StatusBadRequest,
+		},
+		{
+			name:  "malformed signature",
+			query: "shop=shop-name.myshopify.com&signature=zz",
+			code:  http.StatusBadRequest,
+		},
+	}
+
+	for _, tt := range tests {
+		ran := false
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			ran = true
+		})
+
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/apps/awesome_reviews?"+tt.query, nil)
+		AppProxyVerify("hush", nh).ServeHTTP(rec, req)
+
+		if c := rec.Code; c != tt.code {
+			t.Errorf("%s: expected status code %v got %v", tt.name, tt.code, c)
+		}
+
+		if ran != (tt.code == http.StatusOK) {
+			t.Errorf("%s: expected next handler to run only when signed", tt.name)
+		}
+	}
+}
@@ -0,0 +1,91 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits the rate of webhooks accepted per shop.
+type ShopRateLimiter interface {
+	// Allow reports if a webhook from the shop can be handled now.
+	// The context is the request's, cancelled if the client goes away,
+	// so limiters backed by a network store should give up on it.
+	Allow(ctx context.Context, shop string) bool
+}
+
+// Throttle verified webhooks per shop, such as to stop a misbehaving store flooding the app.
+// Webhooks over the limit are rejected with a 429, without running the next handler.
+// Example: `WebhookVerify("abc123", handler, WithRateLimit(NewTokenBucketLimiter(10, 20)))`.
+func WithRateLimit(limiter ShopRateLimiter) Option {
+	return func(cfg *config) {
+		cfg.rateLimiter = limiter
+	}
+}
+
+// Check the shop is within its rate limit, if one is set.
+func checkRateLimit(cfg *config, r *http.Request, shop string) error {
+	if cfg.rateLimiter == nil || cfg.rateLimiter.Allow(r.Context(), normalizeShop(shop)) {
+		return nil
+	}
+
+	return ErrRateLimited
+}
+
+// In-memory token bucket per shop.
+// Each shop starts with a full bucket, refilled at the rate up to the burst.
+// Only limits within a single process.
+type TokenBucketLimiter struct {
+	mu      sync.Mutex
+	rate    float64
+	burst   float64
+	buckets map[string]*tokenBucket
+	now     func() time.Time
+}
+
+// Tokens left in a bucket and when they were last counted.
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Create a token bucket limiter allowing the rate of webhooks per second for each shop,
+// with bursts of up to the burst.
+// Example: `NewTokenBucketLimiter(10, 20)`.
+func NewTokenBucketLimiter(rate float64, burst int) *TokenBucketLimiter {
+	return &TokenBucketLimiter{
+		rate:    rate,
+		burst:   float64(burst),
+		buckets: make(map[string]*tokenBucket),
+		now:     time.Now,
+	}
+}
+
+// Allow takes a token from the shop's bucket, reporting false if it is empty.
+// Nothing blocks, so the context is not used.
+func (l *TokenBucketLimiter) Allow(ctx context.Context, shop string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	b, ok := l.buckets[shop]
+	if !ok {
+		b = &tokenBucket{tokens: l.burst, last: now}
+		l.buckets[shop] = b
+	}
+
+	// Refill for the time passed since the last webhook.
+	b.tokens += now.Sub(b.last).Seconds() * l.rate
+	if b.tokens > l.burst {
+		b.tokens = l.burst
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+
+	return true
+}
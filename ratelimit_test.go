@@ -0,0 +1,133 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// Limiter which denies the listed shops.
+type denyLimiter map[string]bool
+
+func (l denyLimiter) Allow(ctx context.Context, shop string) bool {
+	return !l[shop]
+}
+
+// Runs a request from the shop through the verifier with the options.
+func serveShop(shop string, opts ...Option) (*httptest.ResponseRecorder, bool) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), shop, sign("secret", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, opts...).ServeHTTP(rec, req)
+
+	return rec, ran
+}
+
+// Test shops over their limit are rejected while others pass.
+func TestWithRateLimit(t *testing.T) {
+	opt := WithRateLimit(denyLimiter{"noisy.myshopify.com": true})
+
+	rec, ran := serveShop("noisy.myshopify.com", opt)
+	if c := rec.Code; c != http.StatusTooManyRequests {
+		t.Errorf("expected status code %v got %v", http.StatusTooManyRequests, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+
+	rec, ran = serveShop("example.myshopify.com", opt)
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Test the limit is only applied to verified webhooks.
+func TestRateLimitInvalid(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "noisy.myshopify.com", sign("other", body))
+
+	err := serveForError(req, WithRateLimit(denyLimiter{"noisy.myshopify.com": true}))
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+}
+
+// Test the token bucket allows bursts, then refills at the rate per shop.
+func TestTokenBucketLimiter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	l := NewTokenBucketLimiter(1, 2)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if !l.Allow(context.Background(), "example.myshopify.com") {
+			t.Errorf("expected webhook %v of the burst to be allowed", i+1)
+		}
+	}
+
+	if l.Allow(context.Background(), "example.myshopify.com") {
+		t.Errorf("expected webhook over the burst to be denied")
+	}
+
+	// Other shops have their own bucket.
+	if !l.Allow(context.Background(), "other.myshopify.com") {
+		t.Errorf("expected webhook from another shop to be allowed")
+	}
+
+	// One token is back after a second.
+	now = now.Add(time.Second)
+	if !l.Allow(context.Background(), "example.myshopify.com") {
+		t.Errorf("expected webhook to be allowed after refill")
+	}
+
+	if l.Allow(context.Background(), "example.myshopify.com") {
+		t.Errorf("expected webhook to be denied once refill is used")
+	}
+
+	// Refills do not go over the burst.
+	now = now.Add(time.Hour)
+	for i := 0; i < 2; i++ {
+		l.Allow(context.Background(), "example.myshopify.com")
+	}
+
+	if l.Allow(context.Background(), "example.myshopify.com") {
+		t.Errorf("expected refill to be capped at the burst")
+	}
+}
+
+// Limiter which records the context it was given.
+type ctxLimiter struct {
+	ctx context.Context
+}
+
+func (l *ctxLimiter) Allow(ctx context.Context, shop string) bool {
+	l.ctx = ctx
+	return true
+}
+
+// Test the limiter gets the request context.
+func TestWithRateLimitContext(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)).WithContext(ctx)
+	l := &ctxLimiter{}
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithRateLimit(l)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if l.ctx == nil || l.ctx.Done() != ctx.Done() {
+		t.Errorf("expected the limiter to get the request context")
+	}
+}
@@ -0,0 +1,60 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Recover from panics in the next handler, keeping the server alive.
+// The function receives the recovered value and may write its own response,
+// otherwise a 500 is written if the handler had not started a response.
+// Example: `WebhookVerify("abc123", handler, WithRecover(logPanic))`.
+func WithRecover(fn func(w http.ResponseWriter, r *http.Request, recovered any)) Option {
+	return func(cfg *config) {
+		cfg.recover = fn
+	}
+}
+
+// Run the next handler, recovering from panics if enabled.
+func serveNext(cfg *config, fn http.HandlerFunc, w http.ResponseWriter, r *http.Request) {
+	if cfg.recover == nil {
+		fn(w, r)
+		return
+	}
+
+	sw := &statusWriter{ResponseWriter: w}
+	defer func() {
+		if rec := recover(); rec != nil {
+			cfg.recover(sw, r, rec)
+			if !sw.wrote {
+				http.Error(sw, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}
+	}()
+	fn(sw, r)
+}
+
+// Response writer which tracks if a response was started, and its status.
+type statusWriter struct {
+	http.ResponseWriter
+	wrote bool
+	code  int
+}
+
+func (sw *statusWriter) WriteHeader(code int) {
+	if !sw.wrote {
+		sw.code = code
+	}
+	sw.wrote = true
+	sw.ResponseWriter.WriteHeader(code)
+}
+
+func (sw *statusWriter) Write(b []byte) (int, error) {
+	if !sw.wrote {
+		sw.code = http.StatusOK
+	}
+	sw.wrote = true
+	return sw.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the original response writer, for `http.ResponseController`.
+func (sw *statusWriter) Unwrap() http.ResponseWriter {
+	return sw.ResponseWriter
+}
@@ -0,0 +1,61 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test a panicking handler is recovered with a single 500.
+func TestWithRecover(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	var recovered any
+	rf := func(w http.ResponseWriter, r *http.Request, rec any) {
+		recovered = rec
+	}
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	cw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	WebhookVerify("secret", nh, WithRecover(rf)).ServeHTTP(cw, req)
+
+	if recovered != "boom" {
+		t.Errorf("expected recover function to receive %q got %v", "boom", recovered)
+	}
+
+	if c := cw.Code; c != http.StatusInternalServerError {
+		t.Errorf("expected status code %v got %v", http.StatusInternalServerError, c)
+	}
+
+	if cw.headers != 1 {
+		t.Errorf("expected exactly one response to be written got %v", cw.headers)
+	}
+}
+
+// Test the recover function can choose the response.
+func TestWithRecoverCustomResponse(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	rf := func(w http.ResponseWriter, r *http.Request, rec any) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+
+	cw := &countingWriter{ResponseRecorder: httptest.NewRecorder()}
+	WebhookVerify("secret", nh, WithRecover(rf)).ServeHTTP(cw, req)
+
+	if c := cw.Code; c != http.StatusServiceUnavailable {
+		t.Errorf("expected status code %v got %v", http.StatusServiceUnavailable, c)
+	}
+
+	if cw.headers != 1 {
+		t.Errorf("expected exactly one response to be written got %v", cw.headers)
+	}
+}
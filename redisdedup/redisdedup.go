@@ -0,0 +1,64 @@
+package redisdedup
+
+import (
+	"context"
+	"time"
+
+	"github.com/ohmybrew/http_shopify_webhook"
+)
+
+// Default prefix of the keys webhook IDs are stored under.
+const DefaultPrefix = "shopify:webhook:"
+
+// Subset of a Redis client used by the store.
+// Kept small so any client can be adapted, and the core does not depend on one.
+// For go-redis:
+//
+//	type goRedis struct{ c *redis.Client }
+//
+//	func (g goRedis) SetNX(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+//		return g.c.SetNX(ctx, key, 1, ttl).Result()
+//	}
+//
+//	func (g goRedis) Del(ctx context.Context, key string) error {
+//		return g.c.Del(ctx, key).Err()
+//	}
+type Client interface {
+	// SetNX sets the key, expiring after the TTL, only if it is not already set,
+	// as `SET key 1 NX PX ttl`. Reports if it was set.
+	SetNX(ctx context.Context, key string, ttl time.Duration) (bool, error)
+
+	// Del deletes the key.
+	Del(ctx context.Context, key string) error
+}
+
+// Dedup store of webhook IDs in Redis, shared by every instance of the app.
+type RedisDedupStore struct {
+	client Client
+	prefix string
+}
+
+// Create a store of webhook IDs in Redis, with the keys under the prefix.
+// An empty prefix uses `DefaultPrefix`.
+// Example: `hsw.WithDeduplication(redisdedup.NewRedisDedupStore(goRedis{c}, ""), time.Hour)`.
+func NewRedisDedupStore(client Client, prefix string) *RedisDedupStore {
+	if prefix == "" {
+		prefix = DefaultPrefix
+	}
+
+	return &RedisDedupStore{client: client, prefix: prefix}
+}
+
+// MarkIfAbsent stores the webhook ID, expiring after the TTL, unless it is already stored.
+// Uses a single `SET NX`, so instances racing on the same webhook cannot both mark it.
+func (s *RedisDedupStore) MarkIfAbsent(ctx context.Context, id string, ttl time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+id, ttl)
+}
+
+// Unmark deletes the webhook ID.
+func (s *RedisDedupStore) Unmark(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.prefix+id)
+}
+
+// Ensure the store can be passed to `WithDeduplication`.
+var _ http_shopify_webhook.DedupStore = (*RedisDedupStore)(nil)
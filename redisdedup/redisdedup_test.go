@@ -0,0 +1,104 @@
+package redisdedup
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ohmybrew/http_shopify_webhook"
+)
+
+// In-memory stand in for Redis.
+type fakeClient struct {
+	mu   sync.Mutex
+	keys map[string]time.Duration
+}
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{keys: make(map[string]time.Duration)}
+}
+
+func (c *fakeClient) SetNX(ctx context.Context, key string, ttl time.Duration) (bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.keys[key]; ok {
+		return false, nil
+	}
+	c.keys[key] = ttl
+	return true, nil
+}
+
+func (c *fakeClient) Del(ctx context.Context, key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	delete(c.keys, key)
+	return nil
+}
+
+// Builds a signed request with the webhook ID.
+func newRequest(id string) *http.Request {
+	body := `{"key":"value"}`
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", http_shopify_webhook.ComputeHMAC("secret", []byte(body)))
+	req.Header.Set("X-Shopify-Webhook-Id", id)
+
+	return req
+}
+
+// Test two instances sharing Redis only handle a webhook once.
+func TestRedisDedupStore(t *testing.T) {
+	client := newFakeClient()
+
+	var runs int
+	nh := func(w http.ResponseWriter, r *http.Request) {
+		runs++
+	}
+	a := http_shopify_webhook.WebhookVerify("secret", nh, http_shopify_webhook.WithDeduplication(NewRedisDedupStore(client, ""), time.Hour))
+	b := http_shopify_webhook.WebhookVerify("secret", nh, http_shopify_webhook.WithDeduplication(NewRedisDedupStore(client, ""), time.Hour))
+
+	a.ServeHTTP(httptest.NewRecorder(), newRequest("b54557e4"))
+	rec := httptest.NewRecorder()
+	b.ServeHTTP(rec, newRequest("b54557e4"))
+
+	if runs != 1 {
+		t.Errorf("expected the webhook to run once across instances got %v", runs)
+	}
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected duplicate to have status code %v got %v", http.StatusOK, c)
+	}
+
+	if ttl, ok := client.keys[DefaultPrefix+"b54557e4"]; !ok || ttl != time.Hour {
+		t.Errorf("expected the ID to be stored under the prefix for %v got %v", time.Hour, ttl)
+	}
+}
+
+// Test a custom prefix is used for the keys.
+func TestRedisDedupStorePrefix(t *testing.T) {
+	client := newFakeClient()
+	s := NewRedisDedupStore(client, "app:")
+
+	ctx := context.Background()
+	if marked, _ := s.MarkIfAbsent(ctx, "b54557e4", time.Minute); !marked {
+		t.Errorf("expected the ID to be marked")
+	}
+	if _, ok := client.keys["app:b54557e4"]; !ok {
+		t.Errorf("expected the ID to be stored under the prefix got %v", client.keys)
+	}
+
+	if marked, _ := s.MarkIfAbsent(ctx, "b54557e4", time.Minute); marked {
+		t.Errorf("expected the ID to already be marked")
+	}
+
+	s.Unmark(ctx, "b54557e4")
+	if _, ok := client.keys["app:b54557e4"]; ok {
+		t.Errorf("expected the ID to be deleted got %v", client.keys)
+	}
+}
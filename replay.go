@@ -0,0 +1,62 @@
+package http_shopify_webhook
+
+import (
+	"net/http"
+	"time"
+)
+
+// Default header holding the time Shopify triggered the webhook.
+const DefaultTimestampHeader = "X-Shopify-Triggered-At"
+
+// How far in the future a webhook timestamp can be, for clocks out of sync.
+const MaxClockSkew = time.Minute
+
+// Reject webhooks triggered longer ago than the duration, or more than `MaxClockSkew`
+// in the future. The timestamp is read from the `X-Shopify-Triggered-At` header as RFC 3339,
+// requests with a missing or malformed timestamp are rejected when enabled.
+// The header is not covered by the HMAC, so a replayed webhook sent with a fresh
+// timestamp still passes. This only rejects stale deliveries, such as from a stuck queue.
+// Example: `WebhookVerify("abc123", handler, WithMaxAge(5 * time.Minute))`.
+func WithMaxAge(d time.Duration) Option {
+	return func(cfg *config) {
+		cfg.maxAge = d
+	}
+}
+
+// Read the timestamp for `WithMaxAge` from a different header.
+// Example: `WebhookVerify("abc123", handler, WithMaxAge(time.Minute), WithTimestampHeader("X-Sent-At"))`.
+func WithTimestampHeader(name string) Option {
+	return func(cfg *config) {
+		cfg.timestampHeader = name
+	}
+}
+
+// Check the age of the request against the max age, if enabled.
+func checkAge(cfg *config, r *http.Request) error {
+	if cfg.maxAge <= 0 {
+		// Not enabled.
+		return nil
+	}
+
+	ts := r.Header.Get(cfg.timestampHeader)
+	if ts == "" {
+		return ErrMissingTimestamp
+	}
+
+	at, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+
+	age := cfg.now().Sub(at)
+	if age > cfg.maxAge {
+		// Too old, possibly replayed.
+		return ErrExpired
+	}
+	if age < -MaxClockSkew {
+		// Set ahead to stay fresh for longer.
+		return ErrInvalidTimestamp
+	}
+
+	return nil
+}
@@ -0,0 +1,71 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Option to fix the clock for tests.
+func withNow(now time.Time) Option {
+	return WithClock(func() time.Time {
+		return now
+	})
+}
+
+// Test the request age is checked against the max age.
+func TestWithMaxAge(t *testing.T) {
+	body := `{"key":"value"}`
+	now := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		ts   string
+		err  error
+	}{
+		{name: "fresh", ts: "2019-04-01T11:59:00Z", err: nil},
+		{name: "fresh with nanoseconds", ts: "2019-04-01T11:58:30.877041743Z", err: nil},
+		{name: "expired", ts: "2019-04-01T11:50:00Z", err: ErrExpired},
+		{name: "within clock skew", ts: "2019-04-01T12:00:30Z", err: nil},
+		{name: "future", ts: "2019-04-01T12:05:00Z", err: ErrInvalidTimestamp},
+		{name: "malformed", ts: "yesterday", err: ErrInvalidTimestamp},
+		{name: "missing", ts: "", err: ErrMissingTimestamp},
+	}
+
+	for _, tt := range tests {
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+		if tt.ts != "" {
+			req.Header.Set("X-Shopify-Triggered-At", tt.ts)
+		}
+
+		err := serveForError(req, WithMaxAge(5*time.Minute), withNow(now))
+		if !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// Test the timestamp is not required when max age is not set.
+func TestWithoutMaxAge(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	if err := serveForError(req); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
+
+// Test the timestamp can be read from a different header.
+func TestWithTimestampHeader(t *testing.T) {
+	body := `{"key":"value"}`
+	now := time.Date(2019, 4, 1, 12, 0, 0, 0, time.UTC)
+
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	req.Header.Set("X-Sent-At", "2019-04-01T11:50:00Z")
+
+	err := serveForError(req, WithMaxAge(time.Minute), WithTimestampHeader("X-Sent-At"), withNow(now))
+	if !errors.Is(err, ErrExpired) {
+		t.Errorf("expected error %v got %v", ErrExpired, err)
+	}
+}
@@ -0,0 +1,47 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+)
+
+// Request body which can be read more than once, such as by several handlers in the chain.
+// Once read to the end it keeps returning `io.EOF`, the same as any reader,
+// until `Rewind` is called to start it over for the next reader.
+// The verified body is restored as one, but not safe for concurrent reads.
+// Example: `if rb, ok := r.Body.(*RewindableBody); ok { rb.Rewind() }`.
+type RewindableBody struct {
+	r *bytes.Reader
+}
+
+// Create a rewindable body reading the bytes.
+// Example: `r.Body = NewRewindableBody(body)`.
+func NewRewindableBody(bb []byte) *RewindableBody {
+	return &RewindableBody{r: bytes.NewReader(bb)}
+}
+
+// Read reads the body, returning `io.EOF` once the end is reached.
+func (b *RewindableBody) Read(p []byte) (int, error) {
+	return b.r.Read(p)
+}
+
+// Rewind starts the body over from the beginning.
+func (b *RewindableBody) Rewind() {
+	b.r.Seek(0, io.SeekStart)
+}
+
+// Close does nothing, the bytes stay readable after a `Rewind`.
+func (b *RewindableBody) Close() error {
+	return nil
+}
+
+// Put the body back for the next stage of the chain, rewinding it if it is
+// still a rewindable body, otherwise restoring it from the bytes.
+func restoreBody(r *http.Request, bb []byte) {
+	if rb, ok := r.Body.(*RewindableBody); ok {
+		rb.Rewind()
+		return
+	}
+	r.Body = NewRewindableBody(bb)
+}
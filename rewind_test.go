@@ -0,0 +1,80 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the body reads the same each time it is rewound.
+func TestRewindableBody(t *testing.T) {
+	body := `{"key":"value"}`
+	rb := NewRewindableBody([]byte(body))
+
+	for i := 0; i < 2; i++ {
+		bb, err := io.ReadAll(rb)
+		if err != nil {
+			t.Errorf("expected no error got %v", err)
+		}
+
+		if string(bb) != body {
+			t.Errorf("expected body %q on read %v got %q", body, i+1, bb)
+		}
+		rb.Rewind()
+	}
+}
+
+// Test the body keeps returning EOF once read to the end, until rewound.
+func TestRewindableBodyEOF(t *testing.T) {
+	rb := NewRewindableBody([]byte(`{"key":"value"}`))
+	io.ReadAll(rb)
+
+	p := make([]byte, 4)
+	if n, err := rb.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("expected 0 bytes and %v got %v bytes and %v", io.EOF, n, err)
+	}
+
+	// Closing does not start it over.
+	rb.Close()
+	if n, err := rb.Read(p); n != 0 || err != io.EOF {
+		t.Errorf("expected 0 bytes and %v after close got %v bytes and %v", io.EOF, n, err)
+	}
+}
+
+// Test a partly read body starts over once rewound.
+func TestRewindableBodyRewind(t *testing.T) {
+	rb := NewRewindableBody([]byte(`{"key":"value"}`))
+
+	p := make([]byte, 4)
+	rb.Read(p)
+	rb.Rewind()
+
+	bb, _ := io.ReadAll(rb)
+	if string(bb) != `{"key":"value"}` {
+		t.Errorf("expected body %q got %q", `{"key":"value"}`, bb)
+	}
+}
+
+// Test the restored body can be read twice down the chain.
+func TestRewindableBodyRestored(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	// Middleware reading the body and rewinding it for the next handler.
+	var first, second []byte
+	inner := func(w http.ResponseWriter, r *http.Request) {
+		second, _ = io.ReadAll(r.Body)
+	}
+	outer := func(w http.ResponseWriter, r *http.Request) {
+		first, _ = io.ReadAll(r.Body)
+		r.Body.(*RewindableBody).Rewind()
+		inner(w, r)
+	}
+	WebhookVerify("secret", outer).ServeHTTP(httptest.NewRecorder(), req)
+
+	if string(first) != body || string(second) != body {
+		t.Errorf("expected body %q on both reads got %q and %q", body, first, second)
+	}
+}
@@ -0,0 +1,91 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Payload signed by `SelfTest`.
+const selfTestPayload = `{"id":1,"topic":"self/test"}`
+
+// Check a webhook signed with the secret key passes the verifier built from the options,
+// such as at startup. The key signs and verifies the payload alike, so a wrong key is
+// not caught, only an empty one or options which reject every webhook, such as a hash
+// or a header name the signature is not built for.
+// A custom `WithHMACDecoder` has no matching encoder, so the standard one is used.
+// Example: `if err := SelfTest(secret, opts...); err != nil { log.Fatal(err) }`.
+func SelfTest(key string, opts ...Option) error {
+	return selfTest(key, key, opts)
+}
+
+// Sign the payload with the signing key and verify it with the key and options.
+func selfTest(key string, signKey string, opts []Option) error {
+	if key == "" {
+		return ErrMissingSecret
+	}
+
+	cfg := newConfig(opts)
+	cfg.decodeHMAC = decodeHMAC
+	cfg.rateLimiter = nil
+
+	method := http.MethodPost
+	if len(cfg.allowedMethods) > 0 {
+		method = cfg.allowedMethods[0]
+	}
+	body := []byte(selfTestPayload)
+	r, err := http.NewRequest(method, "/", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	contentType := "application/json"
+	if len(cfg.contentTypes) > 0 {
+		contentType = cfg.contentTypes[0]
+	}
+	r.Header.Set("Content-Type", contentType)
+	r.Header.Set(cfg.shopHeader, selfTestShop(cfg))
+	r.Header.Set(cfg.hmacHeader, base64.StdEncoding.EncodeToString(digestHash(cfg.hashFunc, signKey, body)))
+	r.Header.Set(cfg.timestampHeader, cfg.now().UTC().Format(time.RFC3339Nano))
+	for _, name := range cfg.requiredHeaders {
+		if r.Header.Get(name) == "" {
+			r.Header.Set(name, "self-test")
+		}
+	}
+
+	_, _, err = verifyHTTP(staticKeys(key), cfg, new(bytes.Buffer), discardResponseWriter{}, r)
+	if err != nil {
+		return fmt.Errorf("self test failed: %w", err)
+	}
+
+	return nil
+}
+
+// Get the shop the payload is sent from, the first allowed shop if any are set.
+func selfTestShop(cfg *config) string {
+	shop := ""
+	for s := range cfg.allowedShops {
+		if shop == "" || s < shop {
+			shop = s
+		}
+	}
+	if shop == "" {
+		return "self-test.myshopify.com"
+	}
+
+	return shop
+}
+
+// Response writer which discards the response.
+type discardResponseWriter struct{}
+
+func (discardResponseWriter) Header() http.Header {
+	return make(http.Header)
+}
+
+func (discardResponseWriter) Write(b []byte) (int, error) {
+	return len(b), nil
+}
+
+func (discardResponseWriter) WriteHeader(code int) {}
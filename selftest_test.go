@@ -0,0 +1,54 @@
+package http_shopify_webhook
+
+import (
+	"crypto/sha512"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+)
+
+// Test the self test passes for a key.
+func TestSelfTest(t *testing.T) {
+	for _, key := range []string{"secret", "shpss_0123456789abcdef", "ключ"} {
+		if err := SelfTest(key); err != nil {
+			t.Errorf("expected self test to pass for %q got %v", key, err)
+		}
+	}
+}
+
+// Test the self test reports a mismatch.
+func TestSelfTestMismatch(t *testing.T) {
+	err := selfTest("secret", "other", nil)
+	if !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+}
+
+// Test the self test reports an empty key.
+func TestSelfTestEmptyKey(t *testing.T) {
+	if err := SelfTest(""); !errors.Is(err, ErrMissingSecret) {
+		t.Errorf("expected error %v got %v", ErrMissingSecret, err)
+	}
+}
+
+// Test the self test runs through the verifier built from the options.
+func TestSelfTestOptions(t *testing.T) {
+	opts := [][]Option{
+		{WithHashFunc(sha512.New), WithHMACHeader("X-Signature")},
+		{WithAllowedShops("example.myshopify.com"), WithRequiredHeaders("X-Shopify-Topic")},
+		{WithAllowedMethods(http.MethodPut), WithContentType("application/json")},
+		{WithMaxAge(time.Minute)},
+	}
+	for i, o := range opts {
+		if err := SelfTest("secret", o...); err != nil {
+			t.Errorf("expected self test %v to pass got %v", i, err)
+		}
+	}
+
+	// Options which reject every webhook are reported.
+	err := SelfTest("secret", WithMaxBodySize(4))
+	if !errors.Is(err, ErrBodyTooLarge) {
+		t.Errorf("expected error %v got %v", ErrBodyTooLarge, err)
+	}
+}
@@ -0,0 +1,68 @@
+package http_shopify_webhook
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Pattern of a canonical shop domain, such as `example.myshopify.com`.
+var shopDomainPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*\.myshopify\.com$`)
+
+// Reject shop domains which are not a well-formed `*.myshopify.com` domain.
+// Matching ignores case, a domain with a port is rejected.
+// Example: `WebhookVerify("abc123", handler, WithShopDomainValidation())`.
+func WithShopDomainValidation() Option {
+	return WithShopDomainPattern(shopDomainPattern)
+}
+
+// Reject shop domains which do not match the pattern, such as for custom suffixes.
+// The domain is lowercased before matching.
+// Example: `WebhookVerify("abc123", handler, WithShopDomainPattern(shopsPattern))`.
+func WithShopDomainPattern(re *regexp.Regexp) Option {
+	return func(cfg *config) {
+		cfg.shopPattern = re
+	}
+}
+
+// Check the shop domain against the pattern, if set.
+func checkShopDomain(cfg *config, shop string) error {
+	if cfg.shopPattern == nil {
+		return nil
+	}
+
+	if !cfg.shopPattern.MatchString(strings.ToLower(shop)) {
+		return ErrInvalidShopDomain
+	}
+
+	return nil
+}
+
+// Only accept webhooks from the listed shops, others are rejected with a 403 once verified.
+// Matching ignores case and a trailing slash, an empty list allows all shops.
+// Example: `WebhookVerify("abc123", handler, WithAllowedShops("example.myshopify.com"))`.
+func WithAllowedShops(shops ...string) Option {
+	return func(cfg *config) {
+		cfg.allowedShops = make(map[string]bool, len(shops))
+		for _, shop := range shops {
+			cfg.allowedShops[normalizeShop(shop)] = true
+		}
+	}
+}
+
+// Check the shop is in the allowlist, if set.
+func checkAllowedShop(cfg *config, shop string) error {
+	if len(cfg.allowedShops) == 0 {
+		return nil
+	}
+
+	if !cfg.allowedShops[normalizeShop(shop)] {
+		return ErrShopNotAllowed
+	}
+
+	return nil
+}
+
+// Normalize the shop domain for comparison.
+func normalizeShop(shop string) string {
+	return strings.TrimSuffix(strings.ToLower(shop), "/")
+}
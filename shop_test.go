@@ -0,0 +1,94 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+// Test shop domains are validated.
+func TestWithShopDomainValidation(t *testing.T) {
+	tests := []struct {
+		shop string
+		err  error
+	}{
+		{shop: "example.myshopify.com", err: nil},
+		{shop: "my-shop-2.myshopify.com", err: nil},
+		{shop: "Example.MyShopify.com", err: nil},
+		{shop: "example.myshopify.com:443", err: ErrInvalidShopDomain},
+		{shop: "example.myshopify.com.evil.com", err: ErrInvalidShopDomain},
+		{shop: "evil.com", err: ErrInvalidShopDomain},
+		{shop: "-example.myshopify.com", err: ErrInvalidShopDomain},
+		{shop: "", err: ErrMissingShop},
+	}
+
+	body := `{"key":"value"}`
+	for _, tt := range tests {
+		req := newWebhookRequest(bytes.NewBufferString(body), tt.shop, sign("secret", body))
+		if err := serveForError(req, WithShopDomainValidation()); !errors.Is(err, tt.err) {
+			t.Errorf("%q: expected error %v got %v", tt.shop, tt.err, err)
+		}
+	}
+}
+
+// Test shop domains are validated against a custom pattern.
+func TestWithShopDomainPattern(t *testing.T) {
+	body := `{"key":"value"}`
+	opt := WithShopDomainPattern(regexp.MustCompile(`^[a-z0-9-]+\.example\.com$`))
+
+	req := newWebhookRequest(bytes.NewBufferString(body), "shop.example.com", sign("secret", body))
+	if err := serveForError(req, opt); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	if err := serveForError(req, opt); !errors.Is(err, ErrInvalidShopDomain) {
+		t.Errorf("expected error %v got %v", ErrInvalidShopDomain, err)
+	}
+}
+
+// Test only allowed shops are accepted.
+func TestWithAllowedShops(t *testing.T) {
+	tests := []struct {
+		name  string
+		shops []string
+		shop  string
+		err   error
+	}{
+		{name: "allowed", shops: []string{"example.myshopify.com"}, shop: "example.myshopify.com", err: nil},
+		{name: "allowed ignoring case", shops: []string{"Example.myshopify.com/"}, shop: "EXAMPLE.myshopify.com", err: nil},
+		{name: "disallowed", shops: []string{"example.myshopify.com"}, shop: "other.myshopify.com", err: ErrShopNotAllowed},
+		{name: "empty allowlist", shops: nil, shop: "other.myshopify.com", err: nil},
+	}
+
+	body := `{"key":"value"}`
+	for _, tt := range tests {
+		req := newWebhookRequest(bytes.NewBufferString(body), tt.shop, sign("secret", body))
+		if err := serveForError(req, WithAllowedShops(tt.shops...)); !errors.Is(err, tt.err) {
+			t.Errorf("%s: expected error %v got %v", tt.name, tt.err, err)
+		}
+	}
+}
+
+// Test a disallowed shop is rejected with a 403.
+func TestWithAllowedShopsStatus(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "other.myshopify.com", sign("secret", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithAllowedShops("example.myshopify.com")).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusForbidden {
+		t.Errorf("expected status code %v got %v", http.StatusForbidden, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
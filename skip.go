@@ -0,0 +1,19 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Pass requests matching the predicate to the next handler without verifying them,
+// such as ones sent by hand in local development behind an environment flag.
+// Skipped requests are not marked as verified in the context.
+// Never skip based on something the sender controls, such as a header, in production.
+// Example: `WebhookVerify("abc123", handler, WithSkipVerification(func(r *http.Request) bool { return devMode }))`.
+func WithSkipVerification(skip func(r *http.Request) bool) Option {
+	return func(cfg *config) {
+		cfg.skip = skip
+	}
+}
+
+// Check if verification should be skipped for the request.
+func skipVerification(cfg *config, r *http.Request) bool {
+	return cfg.skip != nil && cfg.skip(r)
+}
@@ -0,0 +1,79 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Skip requests with a dev header.
+func skipDev(r *http.Request) bool {
+	return r.Header.Get("X-Dev") == "1"
+}
+
+// Test unsigned requests pass through unverified when skipped.
+func TestWithSkipVerification(t *testing.T) {
+	req := newWebhookRequest(bytes.NewBufferString(`{"key":"value"}`), "", "")
+	req.Header.Set("X-Dev", "1")
+
+	ran := false
+	verified := true
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+		verified = IsVerified(r.Context())
+	}, WithSkipVerification(skipDev)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+
+	if verified {
+		t.Errorf("expected request to not be marked as verified")
+	}
+}
+
+// Test requests are still verified when not skipped.
+func TestSkipVerificationNotSkipped(t *testing.T) {
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	}, WithSkipVerification(skipDev)).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but did")
+	}
+
+	// Signed requests are verified as usual.
+	rec, ran = serveWithOptions(body, WithSkipVerification(skipDev))
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but did not")
+	}
+}
+
+// Test nothing is skipped by default.
+func TestSkipVerificationDefault(t *testing.T) {
+	req := newWebhookRequest(bytes.NewBufferString(`{"key":"value"}`), "", "")
+	req.Header.Set("X-Dev", "1")
+
+	if ok := WebhookVerifyRequest("secret", httptest.NewRecorder(), req); ok {
+		t.Errorf("expected request to be rejected but was not")
+	}
+}
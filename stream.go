@@ -0,0 +1,68 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"strings"
+)
+
+// Verify a webhook body read from the reader, such as from a queue consumer.
+// The body is streamed through the hasher and returned, along with whether it verified.
+// A missing or malformed HMAC is an error, checked before anything is read,
+// as is failing to read the body, wrapping `ErrBodyRead`.
+// Example: `ok, body, err := VerifyReader("abc123", hmacHeader, msg.Body)`.
+func VerifyReader(key string, shmac string, r io.Reader) (bool, []byte, error) {
+	buf := new(bytes.Buffer)
+	ok, err := verifyReader(sha256.New, key, shmac, r, buf)
+	if err != nil {
+		return false, nil, err
+	}
+
+	return ok, buf.Bytes(), nil
+}
+
+// Verify a webhook body read from the reader, copying it to the writer as it is read.
+// Pass a nil writer to not keep the body, so even large bodies are never held in memory.
+// Errors are the same as for `VerifyReader`.
+// Example: `ok, err := VerifyReaderTo("abc123", hmacHeader, msg.Body, file)`.
+func VerifyReaderTo(key string, shmac string, r io.Reader, w io.Writer) (bool, error) {
+	return verifyReader(sha256.New, key, shmac, r, w)
+}
+
+// Verify a saved webhook body read from the reader, such as stdin or a file, for debugging.
+// The same as `VerifyReaderTo` without keeping the body.
+// Example: `ok, err := VerifyStream(os.Getenv("SHOPIFY_SECRET"), os.Args[1], os.Stdin)`.
+func VerifyStream(key string, shmac string, r io.Reader) (bool, error) {
+	return verifyReader(sha256.New, key, shmac, r, nil)
+}
+
+// Check the HMAC, then stream the body through the hasher and the writer and compare it.
+func verifyReader(newHash func() hash.Hash, key string, shmac string, r io.Reader, w io.Writer) (bool, error) {
+	shmac = strings.TrimSpace(shmac)
+	if shmac == "" {
+		return false, ErrMissingHMAC
+	}
+
+	h := hmac.New(newHash, []byte(key))
+
+	// Decode the HMAC from Shopify to raw bytes, compared in constant time.
+	dec, err := decodeHMAC(shmac, h.Size())
+	if err != nil {
+		// Not a valid base64 string, or not a digest.
+		return false, fmt.Errorf("%w: malformed HMAC: %v", ErrInvalidSignature, err)
+	}
+
+	dst := io.Writer(h)
+	if w != nil {
+		dst = io.MultiWriter(h, w)
+	}
+	if _, err := io.Copy(dst, r); err != nil {
+		return false, fmt.Errorf("%w: %v", ErrBodyRead, err)
+	}
+
+	return hmac.Equal(h.Sum(nil), dec), nil
+}
@@ -0,0 +1,139 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+)
+
+// Test streaming verification matches the buffered one.
+func TestVerifyReader(t *testing.T) {
+	body := strings.Repeat(`{"key":"value"}`, 1000)
+	tests := []struct {
+		hmac string
+	}{
+		{hmac: sign("secret", body)},
+		{hmac: sign("other", body)},
+	}
+
+	for _, tt := range tests {
+		want := Verify("secret", tt.hmac, []byte(body))
+
+		ok, bb, err := VerifyReader("secret", tt.hmac, strings.NewReader(body))
+		if err != nil {
+			t.Errorf("expected no error got %v", err)
+		}
+
+		if ok != want {
+			t.Errorf("expected streaming result %v to match buffered result %v", ok, want)
+		}
+
+		if string(bb) != body {
+			t.Errorf("expected body to be captured intact")
+		}
+	}
+}
+
+// Test the body is copied to the writer, or not kept at all.
+func TestVerifyReaderTo(t *testing.T) {
+	body := `{"key":"value"}`
+
+	buf := new(bytes.Buffer)
+	ok, err := VerifyReaderTo("secret", sign("secret", body), strings.NewReader(body), buf)
+	if err != nil || !ok {
+		t.Errorf("expected body to verify got %v and error %v", ok, err)
+	}
+
+	if s := buf.String(); s != body {
+		t.Errorf("expected body %q to be copied got %q", body, s)
+	}
+
+	ok, err = VerifyReaderTo("secret", sign("secret", body), strings.NewReader(body), nil)
+	if err != nil || !ok {
+		t.Errorf("expected body to verify without a writer got %v and error %v", ok, err)
+	}
+}
+
+// Test read failures are returned as errors.
+func TestVerifyReaderError(t *testing.T) {
+	ok, bb, err := VerifyReader("secret", sign("secret", `{"key":"value"}`), &errReader{})
+	if !errors.Is(err, ErrBodyRead) {
+		t.Errorf("expected error %v got %v", ErrBodyRead, err)
+	}
+
+	if ok || bb != nil {
+		t.Errorf("expected no result on error got %v and %q", ok, bb)
+	}
+}
+
+// Test a piped payload verifies only against its own HMAC.
+func TestVerifyStream(t *testing.T) {
+	body := `{"key":"value"}`
+	tests := []struct {
+		name string
+		hmac string
+		ok   bool
+	}{
+		{name: "valid", hmac: sign("secret", body), ok: true},
+		{name: "valid with newline", hmac: sign("secret", body) + "\n", ok: true},
+		{name: "other key", hmac: sign("other", body), ok: false},
+	}
+
+	for _, tt := range tests {
+		ok, err := VerifyStream("secret", tt.hmac, strings.NewReader(body))
+		if err != nil {
+			t.Errorf("%s: expected no error got %v", tt.name, err)
+		}
+
+		if ok != tt.ok {
+			t.Errorf("%s: expected %v got %v", tt.name, tt.ok, ok)
+		}
+	}
+}
+
+// Test a missing or malformed header is an error for every reader, without reading the payload.
+func TestVerifyStreamMalformed(t *testing.T) {
+	tests := []struct {
+		name string
+		hmac string
+		err  error
+	}{
+		{name: "missing", hmac: " ", err: ErrMissingHMAC},
+		{name: "not base64", hmac: "not-base64!", err: ErrInvalidSignature},
+		{name: "hex", hmac: hex.EncodeToString(digest("secret", []byte(`{"key":"value"}`))), err: ErrInvalidSignature},
+	}
+
+	verifiers := map[string]func(shmac string, r io.Reader) (bool, error){
+		"VerifyStream": func(shmac string, r io.Reader) (bool, error) {
+			return VerifyStream("secret", shmac, r)
+		},
+		"VerifyReader": func(shmac string, r io.Reader) (bool, error) {
+			ok, _, err := VerifyReader("secret", shmac, r)
+			return ok, err
+		},
+		"VerifyReaderTo": func(shmac string, r io.Reader) (bool, error) {
+			return VerifyReaderTo("secret", shmac, r, io.Discard)
+		},
+	}
+
+	for fn, verify := range verifiers {
+		for _, tt := range tests {
+			tr := &trackingReader{Reader: strings.NewReader(`{"key":"value"}`)}
+			ok, err := verify(tt.hmac, tr)
+			if !errors.Is(err, tt.err) {
+				t.Errorf("%s %s: expected error %v got %v", fn, tt.name, tt.err, err)
+			}
+
+			if ok {
+				t.Errorf("%s %s: expected payload to not verify", fn, tt.name)
+			}
+
+			if tr.read {
+				t.Errorf("%s %s: expected payload to not be read", fn, tt.name)
+			}
+		}
+	}
+}
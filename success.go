@@ -0,0 +1,23 @@
+package http_shopify_webhook
+
+import "net/http"
+
+// Call the function for every verified webhook, before the next handler runs,
+// such as to record an audit entry. The request has the verified values in its
+// context and its body restored, which is restored again for the next handler.
+// Example: `WebhookVerify("abc123", handler, WithOnSuccess(audit))`.
+func WithOnSuccess(fn func(r *http.Request)) Option {
+	return func(cfg *config) {
+		cfg.onSuccess = fn
+	}
+}
+
+// Call the success callback, if set, restoring the body after it.
+func onSuccess(cfg *config, r *http.Request, bb []byte) {
+	if cfg.onSuccess == nil {
+		return
+	}
+
+	cfg.onSuccess(r)
+	restoreBody(r, bb)
+}
@@ -0,0 +1,57 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test the callback fires once per verified webhook, before the handler, and can read the body.
+func TestWithOnSuccess(t *testing.T) {
+	body := `{"key":"value"}`
+
+	var calls []string
+	var cbBody, handlerBody string
+	h := WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		calls = append(calls, "handler")
+		bb, _ := io.ReadAll(r.Body)
+		handlerBody = string(bb)
+	}, WithOnSuccess(func(r *http.Request) {
+		calls = append(calls, "success")
+		bb, _ := io.ReadAll(r.Body)
+		cbBody = string(bb)
+	}))
+
+	for i := 0; i < 2; i++ {
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+		h.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if len(calls) != 4 || calls[0] != "success" || calls[1] != "handler" || calls[2] != "success" {
+		t.Errorf("expected the callback once before each handler got %v", calls)
+	}
+
+	if cbBody != body {
+		t.Errorf("expected callback to read body %q got %q", body, cbBody)
+	}
+
+	if handlerBody != body {
+		t.Errorf("expected handler to read body %q got %q", body, handlerBody)
+	}
+}
+
+// Test the callback does not fire for rejected webhooks.
+func TestOnSuccessRejected(t *testing.T) {
+	calls := 0
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+	serveForError(req, WithOnSuccess(func(r *http.Request) {
+		calls++
+	}))
+
+	if calls != 0 {
+		t.Errorf("expected the callback to not fire got %v calls", calls)
+	}
+}
@@ -0,0 +1,37 @@
+package http_shopify_webhook
+
+import (
+	"io"
+	"net/http"
+	"sync"
+)
+
+// Write the raw body of verified webhooks to the writer, such as an audit log,
+// before the next handler runs. Writes are serialized across requests.
+// A failing write is logged, if a logger is set, and does not stop the handler.
+// Example: `WebhookVerify("abc123", handler, WithBodyTee(auditFile))`.
+func WithBodyTee(w io.Writer) Option {
+	return func(cfg *config) {
+		cfg.tee = &bodyTee{w: w}
+	}
+}
+
+// Writer for verified bodies, guarded for concurrent requests.
+type bodyTee struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// Write the verified body to the tee, if set.
+func teeBody(cfg *config, r *http.Request, bb []byte) {
+	if cfg.tee == nil {
+		return
+	}
+
+	cfg.tee.mu.Lock()
+	_, err := cfg.tee.w.Write(bb)
+	cfg.tee.mu.Unlock()
+	if err != nil {
+		logTeeFailure(cfg, r, err)
+	}
+}
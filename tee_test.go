@@ -0,0 +1,82 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Writer which always fails.
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+// Runs a signed request through the verifier with the options, returning the body the handler read.
+func serveTee(body string, opts ...Option) (*httptest.ResponseRecorder, string) {
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	var got string
+	rec := httptest.NewRecorder()
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		bb, _ := io.ReadAll(r.Body)
+		got = string(bb)
+	}, opts...).ServeHTTP(rec, req)
+
+	return rec, got
+}
+
+// Test verified bodies are written to the tee and still reach the handler.
+func TestWithBodyTee(t *testing.T) {
+	tee := new(bytes.Buffer)
+	body := `{"key":"value"}`
+
+	_, got := serveTee(body, WithBodyTee(tee))
+	if s := tee.String(); s != body {
+		t.Errorf("expected teed body %q got %q", body, s)
+	}
+
+	if got != body {
+		t.Errorf("expected handler to read body %q got %q", body, got)
+	}
+}
+
+// Test bodies failing verification are not written to the tee.
+func TestBodyTeeInvalid(t *testing.T) {
+	tee := new(bytes.Buffer)
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+	serveForError(req, WithBodyTee(tee))
+
+	if tee.Len() != 0 {
+		t.Errorf("expected nothing to be teed got %q", tee.String())
+	}
+}
+
+// Test a failing tee is logged without stopping the handler.
+func TestBodyTeeFailure(t *testing.T) {
+	ch := &captureHandler{}
+	body := `{"key":"value"}`
+
+	rec, got := serveTee(body, WithBodyTee(failingWriter{}), WithLogger(slog.New(ch)))
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if got != body {
+		t.Errorf("expected handler to read body %q got %q", body, got)
+	}
+
+	if len(ch.records) != 1 {
+		t.Fatalf("expected 1 log record got %v", len(ch.records))
+	}
+
+	if reason := recordAttrs(ch.records[0])["reason"]; reason != "disk full" {
+		t.Errorf("expected reason %q got %q", "disk full", reason)
+	}
+}
@@ -0,0 +1,24 @@
+package testutil_test
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	hsw "github.com/ohmybrew/http_shopify_webhook"
+	"github.com/ohmybrew/http_shopify_webhook/testutil"
+)
+
+// Exercise a handler behind the verifier with a signed request.
+func ExampleSignedRequest() {
+	handler := hsw.WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {
+		topic, _ := hsw.TopicFromContext(r.Context())
+		fmt.Fprintf(w, "Handled %s", topic)
+	})
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, testutil.SignedRequest("secret", "example.myshopify.com", "orders/create", []byte(`{"id":1}`)))
+
+	fmt.Println(rec.Code, rec.Body.String())
+	// Output: 200 Handled orders/create
+}
@@ -0,0 +1,25 @@
+package testutil
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/ohmybrew/http_shopify_webhook"
+)
+
+// Build a webhook request signed with the key, as Shopify would send it.
+// Signed with the same HMAC the verifier computes, so the two never drift.
+// The topic header is left out when empty.
+// Example: `handler.ServeHTTP(rec, SignedRequest("secret", "example.myshopify.com", "orders/create", body))`.
+func SignedRequest(key string, shop string, topic string, body []byte) *http.Request {
+	req := httptest.NewRequest(http.MethodPost, "/webhook", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(http_shopify_webhook.DefaultShopHeader, shop)
+	req.Header.Set(http_shopify_webhook.DefaultHMACHeader, http_shopify_webhook.ComputeHMAC(key, body))
+	if topic != "" {
+		req.Header.Set("X-Shopify-Topic", topic)
+	}
+
+	return req
+}
@@ -0,0 +1,81 @@
+package testutil
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ohmybrew/http_shopify_webhook"
+)
+
+// Start a server behind the verifier, which echoes the topic and body.
+func newVerifiedServer(key string) *httptest.Server {
+	return httptest.NewServer(http_shopify_webhook.WebhookVerify(key, func(w http.ResponseWriter, r *http.Request) {
+		topic, _ := http_shopify_webhook.TopicFromContext(r.Context())
+		bb, _ := io.ReadAll(r.Body)
+		w.Write([]byte(topic + " " + string(bb)))
+	}))
+}
+
+// Test requests sent through the transport pass the verifier.
+func TestSigningTransport(t *testing.T) {
+	srv := newVerifiedServer("secret")
+	defer srv.Close()
+
+	client := &http.Client{Transport: &SigningTransport{Key: "secret", Shop: "example.myshopify.com", Topic: "orders/create"}}
+	res, err := client.Post(srv.URL, "application/json", bytes.NewBufferString(`{"id":1}`))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	defer res.Body.Close()
+
+	if c := res.StatusCode; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	bb, _ := io.ReadAll(res.Body)
+	if b := string(bb); b != `orders/create {"id":1}` {
+		t.Errorf("expected body %q got %q", `orders/create {"id":1}`, b)
+	}
+}
+
+// Test requests signed with another key are rejected by the verifier.
+func TestSigningTransportWrongKey(t *testing.T) {
+	srv := newVerifiedServer("secret")
+	defer srv.Close()
+
+	client := &http.Client{Transport: &SigningTransport{Key: "other", Shop: "example.myshopify.com"}}
+	res, err := client.Post(srv.URL, "application/json", bytes.NewBufferString(`{"id":1}`))
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	res.Body.Close()
+
+	if c := res.StatusCode; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test the original request is left unsigned.
+func TestSigningTransportOriginal(t *testing.T) {
+	srv := newVerifiedServer("secret")
+	defer srv.Close()
+
+	req, _ := http.NewRequest(http.MethodPost, srv.URL, bytes.NewBufferString(`{"id":1}`))
+	tr := &SigningTransport{Key: "secret", Shop: "example.myshopify.com", Base: http.DefaultTransport}
+	res, err := tr.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("expected no error got %v", err)
+	}
+	res.Body.Close()
+
+	if c := res.StatusCode; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if h := req.Header.Get(http_shopify_webhook.DefaultHMACHeader); h != "" {
+		t.Errorf("expected original request to not be signed got %q", h)
+	}
+}
@@ -0,0 +1,31 @@
+package http_shopify_webhook
+
+import (
+	"context"
+	"net/http"
+)
+
+// Hook into the verification, such as for tracing.
+// The start function is called before verification and returns the context for the request,
+// along with a finish function, called with the reason for a failure or nil once verified.
+// The shop from the configured header is in the context, see `ClaimedShopFromContext`.
+// See the `tracing` package for an OpenTelemetry implementation.
+// Example: `WebhookVerify("abc123", handler, WithTraceHook(startSpan))`.
+func WithTraceHook(start func(r *http.Request) (context.Context, func(err error))) Option {
+	return func(cfg *config) {
+		cfg.traceHook = start
+	}
+}
+
+// Start the trace hook for the request, if set.
+// Returns the request with the hook's context and the finish function.
+func startTrace(cfg *config, r *http.Request) (*http.Request, func(err error)) {
+	if cfg.traceHook == nil {
+		return r, func(err error) {}
+	}
+
+	ctx := context.WithValue(r.Context(), claimedShopKey, r.Header.Get(cfg.shopHeader))
+	ctx, finish := cfg.traceHook(r.WithContext(ctx))
+
+	return r.WithContext(ctx), finish
+}
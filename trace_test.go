@@ -0,0 +1,71 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Key for a value set by the trace hook.
+type traceTestKey struct{}
+
+// Test the trace hook wraps the verification.
+func TestWithTraceHook(t *testing.T) {
+	body := `{"key":"value"}`
+	for _, key := range []string{"secret", "other"} {
+		started := false
+		finished := false
+		var ferr error
+		hook := func(r *http.Request) (context.Context, func(err error)) {
+			started = true
+			return context.WithValue(r.Context(), traceTestKey{}, "span"), func(err error) {
+				finished = true
+				ferr = err
+			}
+		}
+
+		var span interface{}
+		nh := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			span = r.Context().Value(traceTestKey{})
+		})
+
+		req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign(key, body))
+		WebhookVerify("secret", nh, WithTraceHook(hook)).ServeHTTP(httptest.NewRecorder(), req)
+
+		if !started || !finished {
+			t.Errorf("%s: expected hook to start and finish", key)
+		}
+
+		if key == "secret" {
+			if ferr != nil {
+				t.Errorf("%s: expected no error got %v", key, ferr)
+			}
+			if span != "span" {
+				t.Errorf("%s: expected context from hook to reach the next handler", key)
+			}
+		} else if !errors.Is(ferr, ErrInvalidSignature) {
+			t.Errorf("%s: expected error %v got %v", key, ErrInvalidSignature, ferr)
+		}
+	}
+}
+
+// Test the trace hook gets the shop from the configured header.
+func TestWithTraceHookShop(t *testing.T) {
+	var shop string
+	hook := func(r *http.Request) (context.Context, func(err error)) {
+		shop, _ = ClaimedShopFromContext(r.Context())
+		return r.Context(), func(err error) {}
+	}
+
+	body := `{"key":"value"}`
+	req := newWebhookRequest(bytes.NewBufferString(body), "", sign("secret", body))
+	req.Header.Set("X-Proxy-Shop", "example.myshopify.com")
+	WebhookVerify("secret", func(w http.ResponseWriter, r *http.Request) {}, WithShopHeader("X-Proxy-Shop"), WithTraceHook(hook)).ServeHTTP(httptest.NewRecorder(), req)
+
+	if shop != "example.myshopify.com" {
+		t.Errorf("expected shop %q got %q", "example.myshopify.com", shop)
+	}
+}
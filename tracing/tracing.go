@@ -0,0 +1,43 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/ohmybrew/http_shopify_webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Name of the span around the verification.
+const SpanName = "shopify.webhook.verify"
+
+// OpenTelemetry tracing for the verification.
+// Starts a span with the shop and topic, recording if the webhook was verified.
+// The shop is read from the header set with `WithShopHeader`, before it is verified.
+// Failed verifications set the span status to an error.
+// Kept in its own module so the core does not depend on OpenTelemetry.
+// Example: `hsw.WebhookVerify("abc123", handler, tracing.WithTracer(otel.Tracer("webhooks")))`.
+func WithTracer(tracer trace.Tracer) http_shopify_webhook.Option {
+	return http_shopify_webhook.WithTraceHook(func(r *http.Request) (context.Context, func(err error)) {
+		shop, _ := http_shopify_webhook.ClaimedShopFromContext(r.Context())
+		ctx, span := tracer.Start(
+			r.Context(),
+			SpanName,
+			trace.WithAttributes(
+				attribute.String("shopify.shop", shop),
+				attribute.String("shopify.topic", r.Header.Get("X-Shopify-Topic")),
+			),
+		)
+
+		return ctx, func(err error) {
+			span.SetAttributes(attribute.Bool("shopify.verified", err == nil))
+			if err != nil {
+				span.RecordError(err)
+				span.SetStatus(codes.Error, err.Error())
+			}
+			span.End()
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package tracing
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ohmybrew/http_shopify_webhook"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// Test success.
+func TestTracingSuccess(t *testing.T) {
+	span := setupServer(t, "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	attrs := spanAttrs(span)
+	if attrs["shopify.shop"] != attribute.StringValue("example.myshopify.com") {
+		t.Errorf("expected shop attribute got %v", attrs["shopify.shop"].Emit())
+	}
+
+	if attrs["shopify.topic"] != attribute.StringValue("orders/create") {
+		t.Errorf("expected topic attribute got %v", attrs["shopify.topic"].Emit())
+	}
+
+	if attrs["shopify.verified"] != attribute.BoolValue(true) {
+		t.Errorf("expected verified attribute to be true")
+	}
+
+	if c := span.Status().Code; c != codes.Unset {
+		t.Errorf("expected status code %v got %v", codes.Unset, c)
+	}
+}
+
+// Test failure.
+func TestTracingFailure(t *testing.T) {
+	span := setupServer(t, "7iASoA8WSbw19M/h+")
+
+	if attrs := spanAttrs(span); attrs["shopify.verified"] != attribute.BoolValue(false) {
+		t.Errorf("expected verified attribute to be false")
+	}
+
+	if c := span.Status().Code; c != codes.Error {
+		t.Errorf("expected status code %v got %v", codes.Error, c)
+	}
+}
+
+// Test the shop is read from the configured header.
+func TestTracingShopHeader(t *testing.T) {
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set("X-Proxy-Shop", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	nh := func(w http.ResponseWriter, r *http.Request) {}
+	http_shopify_webhook.WebhookVerify("secret", nh, http_shopify_webhook.WithShopHeader("X-Proxy-Shop"), WithTracer(tp.Tracer("test"))).ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span got %v", len(spans))
+	}
+	if attrs := spanAttrs(spans[0]); attrs["shopify.shop"] != attribute.StringValue("example.myshopify.com") {
+		t.Errorf("expected shop attribute got %v", attrs["shopify.shop"].Emit())
+	}
+}
+
+// Get the attributes of the span by key.
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+
+	return attrs
+}
+
+// Sets up the server for a few tests, returning the recorded span.
+func setupServer(t *testing.T, hmac string) sdktrace.ReadOnlySpan {
+	// Record the spans in memory.
+	sr := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(sr))
+
+	// Setup the recorder and request.
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Topic", "orders/create")
+	req.Header.Set("X-Shopify-Hmac-Sha256", hmac)
+
+	nh := func(w http.ResponseWriter, r *http.Request) {}
+	http_shopify_webhook.WebhookVerify("secret", nh, WithTracer(tp.Tracer("test"))).ServeHTTP(rec, req)
+
+	spans := sr.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected one span got %v", len(spans))
+	}
+	if n := spans[0].Name(); n != SpanName {
+		t.Errorf("expected span name %q got %q", SpanName, n)
+	}
+
+	return spans[0]
+}
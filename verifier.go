@@ -0,0 +1,57 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"strings"
+)
+
+// Verifier built once from the key and options, for verifying webhooks
+// from many places, such as HTTP handlers, queue consumers, and CLI tools.
+// Safe for concurrent use.
+type Verifier struct {
+	keys   []string
+	lookup secretLookup
+	cfg    *config
+}
+
+// Create a verifier with the secret key for the Shopify app and the options.
+// Panics if the key is empty, the same as `WebhookVerify`.
+// Example: `v := NewVerifier("abc123", WithMaxBodySize(1 << 20))`.
+func NewVerifier(key string, opts ...Option) *Verifier {
+	return &Verifier{
+		keys:   []string{key},
+		lookup: staticKeys(key),
+		cfg:    newConfig(opts),
+	}
+}
+
+// VerifyRequest verifies the request, returning the reason for the failure, if any.
+// Nothing is written in response, and the body is put back for reading again.
+// Example: `if err := v.VerifyRequest(r); err != nil { ... }`.
+func (v *Verifier) VerifyRequest(r *http.Request) error {
+	buf := new(bytes.Buffer)
+	_, _, err := verifyHTTP(v.lookup, v.cfg, buf, discardResponseWriter{}, r)
+
+	return err
+}
+
+// VerifyBytes verifies the HMAC of the body, such as for webhooks pulled from a queue.
+// Returns the reason for the failure, if any.
+// Example: `if err := v.VerifyBytes(hmacHeader, body); err != nil { ... }`.
+func (v *Verifier) VerifyBytes(shmac string, bb []byte) error {
+	shmac = strings.TrimSpace(shmac)
+	if shmac == "" {
+		return ErrMissingHMAC
+	}
+
+	return verifyKeys(v.cfg.hashFunc, v.cfg.decodeHMAC, v.keys, shmac, bb)
+}
+
+// Middleware verifies the requests before running the next handler, the same as `WebhookVerify`.
+// Example: `http.HandleFunc("/webhook", v.Middleware()(handler))`.
+func (v *Verifier) Middleware() Middleware {
+	return func(fn http.HandlerFunc) http.HandlerFunc {
+		return webhookVerifyConfig(v.lookup, v.cfg, fn)
+	}
+}
@@ -0,0 +1,80 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// Test each entry point of one verifier shares its options.
+func TestVerifier(t *testing.T) {
+	v := NewVerifier("secret", WithShopDomainValidation())
+	body := `{"key":"value"}`
+
+	// Request.
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+	if err := v.VerifyRequest(req); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	bb, _ := io.ReadAll(req.Body)
+	if string(bb) != body {
+		t.Errorf("expected body %q to be put back got %q", body, bb)
+	}
+
+	req = newWebhookRequest(bytes.NewBufferString(body), "example.com", sign("secret", body))
+	if err := v.VerifyRequest(req); !errors.Is(err, ErrInvalidShopDomain) {
+		t.Errorf("expected error %v got %v", ErrInvalidShopDomain, err)
+	}
+
+	// Bytes.
+	if err := v.VerifyBytes(sign("secret", body), []byte(body)); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+
+	if err := v.VerifyBytes(sign("other", body), []byte(body)); !errors.Is(err, ErrInvalidSignature) {
+		t.Errorf("expected error %v got %v", ErrInvalidSignature, err)
+	}
+
+	if err := v.VerifyBytes("", []byte(body)); !errors.Is(err, ErrMissingHMAC) {
+		t.Errorf("expected error %v got %v", ErrMissingHMAC, err)
+	}
+
+	// Middleware.
+	ran := false
+	h := v.Middleware()(func(w http.ResponseWriter, r *http.Request) {
+		ran = true
+	})
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body)))
+	if c := rec.Code; c != http.StatusOK || !ran {
+		t.Errorf("expected status code %v and the handler to run got %v", http.StatusOK, c)
+	}
+
+	rec = httptest.NewRecorder()
+	h.ServeHTTP(rec, newWebhookRequest(bytes.NewBufferString(body), "example.com", sign("secret", body)))
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+}
+
+// Test the verifier panics on an empty key.
+func TestVerifierEmptyKey(t *testing.T) {
+	assertMissingSecretPanic(t, "NewVerifier", func() {
+		NewVerifier("")
+	})
+}
+
+// Test the verifier uses the configured HMAC decoder for bytes.
+func TestVerifierHMACDecoder(t *testing.T) {
+	v := NewVerifier("secret", WithHMACDecoder(hex.DecodeString))
+
+	if err := v.VerifyBytes(hex.EncodeToString(digest("secret", []byte("abc"))), []byte("abc")); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+}
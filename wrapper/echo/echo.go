@@ -1,23 +1,66 @@
 package echo
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/labstack/echo"
 	"github.com/ohmybrew/http_shopify_webhook"
 )
 
+// Key for the Echo state in the request context.
+type stateKey struct{}
+
+// Echo request state passed through the verifier.
+type state struct {
+	ctx echo.Context
+	ran bool
+	err error
+}
+
 // Compatible wrapper for Echo framework.
+// When the handler does not run, the response the verifier wrote, such as a rejection
+// or a 200 for a duplicate, is left as is. A HTTP error is only returned if nothing
+// was written. The body is restored for binding with `c.Bind`. An error from the chain
+// is rendered inside the verifier, so options such as `WithDeduplication` see its status.
 // Example: `e.Use(WebhookVerify("secret"))`.
-func WebhookVerify(key string) func(n echo.HandlerFunc) echo.HandlerFunc {
+func WebhookVerify(key string, opts ...http_shopify_webhook.Option) echo.MiddlewareFunc {
 	return func(n echo.HandlerFunc) echo.HandlerFunc {
+		// Build the verifier once, the Echo context is carried on the request.
+		h := http_shopify_webhook.WebhookVerify(key, func(w http.ResponseWriter, r *http.Request) {
+			s := r.Context().Value(stateKey{}).(*state)
+			s.ran = true
+
+			// Continue the chain with the verified request.
+			s.ctx.SetRequest(r)
+			if s.err = n(s.ctx); s.err != nil {
+				// Render the error here, so the verifier sees its status.
+				s.ctx.Error(s.err)
+			}
+		}, opts...)
+
 		return func(c echo.Context) error {
-			ok := http_shopify_webhook.WebhookVerifyRequest(key, c.Response(), c.Request())
-			if !ok {
+			s := &state{ctx: c}
+			h(responseWriter{c.Response()}, c.Request().WithContext(context.WithValue(c.Request().Context(), stateKey{}, s)))
+			if !s.ran {
+				if c.Response().Committed {
+					// Response was already written by the verifier.
+					return nil
+				}
 				return echo.NewHTTPError(http.StatusBadRequest, "Invalid webhook request")
 			}
 
-			return n(c)
+			return s.err
 		}
 	}
 }
+
+// Response writer reporting the Echo status, for the verifier.
+type responseWriter struct {
+	*echo.Response
+}
+
+// Status reports the status of the Echo response.
+func (w responseWriter) Status() int {
+	return w.Response.Status
+}
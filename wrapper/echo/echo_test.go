@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo"
+	"github.com/ohmybrew/http_shopify_webhook"
 )
 
 // Test success.
@@ -49,6 +51,153 @@ func TestGinWrapperFailure(t *testing.T) {
 	}
 }
 
+// Test the verified shop is passed along in the request context.
+func TestEchoWrapperShopContext(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	var shop string
+	nh := func(c echo.Context) error {
+		shop, _ = http_shopify_webhook.ShopFromContext(c.Request().Context())
+		return nil
+	}
+
+	e := echo.New()
+	c := e.NewContext(req, rec)
+	WebhookVerify("secret")(nh)(c)
+
+	if shop != "example.myshopify.com" {
+		t.Errorf("expected shop %q got %q", "example.myshopify.com", shop)
+	}
+}
+
+// Test through an Echo instance, the body can still be bound after verification.
+func TestEchoWrapperBind(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	var payload struct {
+		Key string `json:"key"`
+	}
+	e := echo.New()
+	e.Use(WebhookVerify("secret"))
+	e.POST("/webhook/order-create", func(c echo.Context) error {
+		if err := c.Bind(&payload); err != nil {
+			t.Errorf("expected body to bind got %v", err)
+		}
+
+		return c.String(http.StatusOK, "Ok")
+	})
+	e.ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if payload.Key != "value" {
+		t.Errorf("expected payload key to be %q got %q", "value", payload.Key)
+	}
+}
+
+// Test through an Echo instance, failures return a single bad request.
+func TestEchoWrapperInstanceFailure(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+")
+
+	ran := false
+	e := echo.New()
+	e.Use(WebhookVerify("secret"))
+	e.POST("/webhook/order-create", func(c echo.Context) error {
+		ran = true
+		return nil
+	})
+	e.ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
+
+// Test responses already written by the verifier are not followed by an error.
+func TestEchoWrapperCommitted(t *testing.T) {
+	body := "{\"key\":\"value\"}"
+	e := echo.New()
+	nh := func(c echo.Context) error {
+		return nil
+	}
+
+	h := WebhookVerify("secret", http_shopify_webhook.WithDeduplication(http_shopify_webhook.NewMemoryDedupStore(), time.Hour))(nh)
+	// First delivery is handled, the duplicate answered by the verifier.
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+		req.Header.Set("X-Shopify-Webhook-Id", "b54557e4")
+
+		if err := h(e.NewContext(req, rec)); err != nil {
+			t.Errorf("expected no error got %v", err)
+		}
+		if c := rec.Code; c != http.StatusOK {
+			t.Errorf("expected status code %v got %v", http.StatusOK, c)
+		}
+	}
+
+	// Rejected on its method.
+	rec := httptest.NewRecorder()
+	if err := h(e.NewContext(httptest.NewRequest(http.MethodGet, "/webhook/order-create", nil), rec)); err != nil {
+		t.Errorf("expected no error got %v", err)
+	}
+	if c := rec.Code; c != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %v got %v", http.StatusMethodNotAllowed, c)
+	}
+}
+
+// Test a delivery the handler fails is handled again on its retry.
+func TestEchoWrapperDeduplicationFailure(t *testing.T) {
+	body := "{\"key\":\"value\"}"
+	e := echo.New()
+	runs := 0
+	nh := func(c echo.Context) error {
+		runs++
+		if runs == 1 {
+			return echo.NewHTTPError(http.StatusInternalServerError, "Database unavailable")
+		}
+		return c.String(http.StatusOK, "Ok")
+	}
+
+	h := WebhookVerify("secret", http_shopify_webhook.WithDeduplication(http_shopify_webhook.NewMemoryDedupStore(), time.Hour))(nh)
+	codes := []int{}
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+		req.Header.Set("X-Shopify-Webhook-Id", "b54557e4")
+
+		h(e.NewContext(req, rec))
+		codes = append(codes, rec.Code)
+	}
+
+	if runs != 2 {
+		t.Errorf("expected the retry of a failed delivery to run got %v runs", runs)
+	}
+	if codes[0] != http.StatusInternalServerError || codes[1] != http.StatusOK {
+		t.Errorf("expected status codes %v then %v got %v", http.StatusInternalServerError, http.StatusOK, codes)
+	}
+}
+
 // Sets up the server for a few tests.
 func setupServer(key string, shop string, hmac string, body string) (*httptest.ResponseRecorder, bool) {
 	// Setup the recorder and request.
@@ -0,0 +1,92 @@
+package fiber
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/gofiber/fiber/v2/middleware/adaptor"
+	"github.com/ohmybrew/http_shopify_webhook"
+)
+
+// Key for the Fiber state in the request context.
+type stateKey struct{}
+
+// Fiber request state passed through the verifier.
+type state struct {
+	ctx *fiber.Ctx
+}
+
+// Compatible wrapper for Fiber framework.
+// Fiber is built on fasthttp rather than net/http, so the request is adapted for the
+// verifier, which writes the response on failure. The body stays readable with `c.Body()`,
+// and the verified values are in `c.UserContext()`, such as for `ShopFromContext`.
+// The rest of the chain runs inside the verifier, so options such as `WithDeduplication`
+// and `WithRecover` see its status and panics. Errors it returns are rendered with the
+// app's error handler there, and not returned again.
+// Panics if the key is empty, the same as `WebhookVerify`.
+// Example: `app.Use(WebhookVerify("secret"))`.
+func WebhookVerify(key string, opts ...http_shopify_webhook.Option) fiber.Handler {
+	// Build the verifier once, the Fiber context is carried on the request.
+	h := http_shopify_webhook.WebhookVerify(key, func(w http.ResponseWriter, r *http.Request) {
+		c := r.Context().Value(stateKey{}).(*state).ctx
+
+		// Continue the chain with the verified values.
+		c.SetUserContext(r.Context())
+		if err := c.Next(); err != nil {
+			if err := c.App().ErrorHandler(c, err); err != nil {
+				http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			}
+		}
+	}, opts...)
+
+	return func(c *fiber.Ctx) error {
+		r, err := adaptor.ConvertRequest(c, true)
+		if err != nil {
+			return err
+		}
+
+		s := &state{ctx: c}
+		h(&responseWriter{ctx: c, header: make(http.Header)}, r.WithContext(context.WithValue(c.UserContext(), stateKey{}, s)))
+
+		return nil
+	}
+}
+
+// Response writer onto the Fiber response, for the verifier.
+type responseWriter struct {
+	ctx    *fiber.Ctx
+	header http.Header
+	wrote  bool
+}
+
+func (w *responseWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *responseWriter) WriteHeader(code int) {
+	if w.wrote {
+		return
+	}
+	w.wrote = true
+
+	for k, vv := range w.header {
+		for _, v := range vv {
+			w.ctx.Response().Header.Add(k, v)
+		}
+	}
+	w.ctx.Status(code)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	if !w.wrote {
+		w.WriteHeader(http.StatusOK)
+	}
+
+	return w.ctx.Response().BodyWriter().Write(b)
+}
+
+// Status reports the status of the Fiber response, including one set later in the chain.
+func (w *responseWriter) Status() int {
+	return w.ctx.Response().StatusCode()
+}
@@ -0,0 +1,208 @@
+package fiber
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/ohmybrew/http_shopify_webhook"
+)
+
+// Test success.
+func TestFiberWrapperSuccess(t *testing.T) {
+	// Set our data.
+	key := "secret"
+	body := "{\"key\":\"value\"}"
+	hmac := "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs="
+	shop := "example.myshopify.com"
+
+	// Setup the server with our data.
+	code, ran := setupServer(t, key, shop, hmac, body)
+
+	if code != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, code)
+	}
+
+	if !ran {
+		t.Errorf("expected next handler to run but it did not")
+	}
+}
+
+// Test failure.
+func TestFiberWrapperFailure(t *testing.T) {
+	// Set our data.
+	key := "secret"
+	body := "{\"key\":\"value\"}"
+	hmac := "7iASoA8WSbw19M/h+"
+	shop := "example.myshopify.com"
+
+	// Setup the server with our data.
+	code, ran := setupServer(t, key, shop, hmac, body)
+
+	if code != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, code)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
+
+// Test failure for an unsigned request.
+func TestFiberWrapperUnsigned(t *testing.T) {
+	code, ran := setupServer(t, "secret", "example.myshopify.com", "", "{\"key\":\"value\"}")
+
+	if code != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, code)
+	}
+
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
+
+// Test core options apply and the verified shop is in the user context.
+func TestFiberWrapperOptions(t *testing.T) {
+	body := "{\"key\":\"value\"}"
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+	req.Header.Set("X-Proxy-Shop", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	var shop string
+	app := fiber.New()
+	app.Use(WebhookVerify("secret", http_shopify_webhook.WithShopHeader("X-Proxy-Shop")))
+	app.Post("/webhook/order-create", func(c *fiber.Ctx) error {
+		shop, _ = http_shopify_webhook.ShopFromContext(c.UserContext())
+		return c.SendString("Ok")
+	})
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("expected request to be served got %v", err)
+	}
+	if c := resp.StatusCode; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+	if shop != "example.myshopify.com" {
+		t.Errorf("expected shop %q got %q", "example.myshopify.com", shop)
+	}
+}
+
+// Test a rejection is written by the verifier, such as a 405 for the method.
+func TestFiberWrapperMethod(t *testing.T) {
+	ran := false
+	app := fiber.New()
+	app.Use(WebhookVerify("secret"))
+	app.Get("/webhook/order-create", func(c *fiber.Ctx) error {
+		ran = true
+		return nil
+	})
+
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/webhook/order-create", nil))
+	if err != nil {
+		t.Fatalf("expected request to be served got %v", err)
+	}
+	if c := resp.StatusCode; c != http.StatusMethodNotAllowed {
+		t.Errorf("expected status code %v got %v", http.StatusMethodNotAllowed, c)
+	}
+	if ran {
+		t.Errorf("expected next handler to not run but it did")
+	}
+}
+
+// Sets up the server for a few tests.
+func setupServer(t *testing.T, key string, shop string, hmac string, body string) (int, bool) {
+	// Setup the request.
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+
+	// Set the headers.
+	req.Header.Set("X-Shopify-Shop-Domain", shop)
+	req.Header.Set("X-Shopify-Hmac-Sha256", hmac)
+
+	// Set the handler for the request.
+	ran := false
+	nh := func(c *fiber.Ctx) error {
+		ran = true
+		if b := string(c.Body()); b != body {
+			t.Errorf("expected body %q got %q", body, b)
+		}
+
+		return c.SendString("Ok")
+	}
+
+	// Start Fiber.
+	app := fiber.New()
+	app.Use(WebhookVerify(key))
+	app.Post("/webhook/order-create", nh)
+
+	resp, err := app.Test(req)
+	if err != nil {
+		t.Fatalf("expected request to be served got %v", err)
+	}
+
+	return resp.StatusCode, ran
+}
+
+// Test an empty key panics when building the handler.
+func TestFiberWrapperEmptyKey(t *testing.T) {
+	defer func() {
+		if rec := recover(); rec == nil {
+			t.Errorf("expected a panic for an empty key but did not")
+		}
+	}()
+	WebhookVerify("")
+}
+
+// Test a delivery which a later handler fails, or panics on, is handled again on its retry.
+func TestFiberWrapperDeduplicationFailure(t *testing.T) {
+	body := "{\"key\":\"value\"}"
+	handlers := map[string]func(c *fiber.Ctx) error{
+		"error": func(c *fiber.Ctx) error {
+			return fiber.NewError(http.StatusInternalServerError, "Database unavailable")
+		},
+		"panic": func(c *fiber.Ctx) error {
+			panic("boom")
+		},
+	}
+
+	for name, fail := range handlers {
+		runs := 0
+		app := fiber.New()
+		app.Use(WebhookVerify(
+			"secret",
+			http_shopify_webhook.WithDeduplication(http_shopify_webhook.NewMemoryDedupStore(), time.Hour),
+			http_shopify_webhook.WithRecover(func(w http.ResponseWriter, r *http.Request, recovered any) {}),
+		))
+		app.Post("/webhook/order-create", func(c *fiber.Ctx) error {
+			runs++
+			if runs == 1 {
+				return fail(c)
+			}
+			return c.SendString("Ok")
+		})
+
+		codes := []int{}
+		for i := 0; i < 2; i++ {
+			req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+			req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+			req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+			req.Header.Set("X-Shopify-Webhook-Id", "b54557e4")
+
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("%s: expected request to be served got %v", name, err)
+			}
+			codes = append(codes, resp.StatusCode)
+		}
+
+		if runs != 2 {
+			t.Errorf("%s: expected the retry of a failed delivery to run got %v runs", name, runs)
+		}
+		if codes[0] != http.StatusInternalServerError || codes[1] != http.StatusOK {
+			t.Errorf("%s: expected status codes %v then %v got %v", name, http.StatusInternalServerError, http.StatusOK, codes)
+		}
+	}
+}
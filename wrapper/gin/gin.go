@@ -1,19 +1,42 @@
 package gin
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"github.com/ohmybrew/http_shopify_webhook"
 )
 
+// Key for the Gin state in the request context.
+type stateKey struct{}
+
+// Gin request state passed through the verifier.
+type state struct {
+	ctx *gin.Context
+	ran bool
+}
+
 // Compatible wrapper for Gin framework.
-// Example: `g.Use(WebhookVerify("secret")`.
-func WebhookVerify(key string) gin.HandlerFunc {
+// Aborts the chain on failure, the body is restored for binding with `c.ShouldBindJSON`.
+// Example: `g.Use(WebhookVerify("secret"))`.
+func WebhookVerify(key string, opts ...http_shopify_webhook.Option) gin.HandlerFunc {
+	// Build the verifier once, the Gin context is carried on the request.
+	h := http_shopify_webhook.WebhookVerify(key, func(w http.ResponseWriter, r *http.Request) {
+		s := r.Context().Value(stateKey{}).(*state)
+		s.ran = true
+
+		// Continue the chain with the verified request.
+		s.ctx.Request = r
+		s.ctx.Next()
+	}, opts...)
+
 	return func(ctx *gin.Context) {
-		ok := http_shopify_webhook.WebhookVerifyRequest(key, ctx.Writer, ctx.Request)
-		if !ok {
-			ctx.AbortWithStatus(http.StatusBadRequest)
+		s := &state{ctx: ctx}
+		h(ctx.Writer, ctx.Request.WithContext(context.WithValue(ctx.Request.Context(), stateKey{}, s)))
+		if !s.ran {
+			// Response was already written by the verifier, only stop the chain.
+			ctx.Abort()
 		}
 	}
 }
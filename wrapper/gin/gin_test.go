@@ -5,8 +5,10 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"testing"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/ohmybrew/http_shopify_webhook"
 )
 
 // Test success.
@@ -49,6 +51,76 @@ func TestGinWrapperFailure(t *testing.T) {
 	}
 }
 
+// Test the verified shop is passed along in the request context.
+func TestGinWrapperShopContext(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	var shop string
+	r := gin.New()
+	r.Use(WebhookVerify("secret"))
+	r.POST("/webhook/order-create", func(c *gin.Context) {
+		shop, _ = http_shopify_webhook.ShopFromContext(c.Request.Context())
+	})
+	r.ServeHTTP(rec, req)
+
+	if shop != "example.myshopify.com" {
+		t.Errorf("expected shop %q got %q", "example.myshopify.com", shop)
+	}
+}
+
+// Test the body can still be bound after verification.
+func TestGinWrapperBindJSON(t *testing.T) {
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString("{\"key\":\"value\"}"))
+	req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+	req.Header.Set("X-Shopify-Hmac-Sha256", "7iASoA8WSbw19M/h+lgrLr2ly/LvgnE9bcLsk9gflvs=")
+
+	var payload struct {
+		Key string `json:"key"`
+	}
+	r := gin.New()
+	r.Use(WebhookVerify("secret"))
+	r.POST("/webhook/order-create", func(c *gin.Context) {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			t.Errorf("expected body to bind got %v", err)
+		}
+	})
+	r.ServeHTTP(rec, req)
+
+	if payload.Key != "value" {
+		t.Errorf("expected payload key to be %q got %q", "value", payload.Key)
+	}
+}
+
+// Test a delivery which the Gin handler fails is handled again on its retry.
+func TestGinWrapperDeduplicationFailure(t *testing.T) {
+	body := "{\"key\":\"value\"}"
+	runs := 0
+	r := gin.New()
+	r.Use(WebhookVerify("secret", http_shopify_webhook.WithDeduplication(http_shopify_webhook.NewMemoryDedupStore(), time.Hour)))
+	r.POST("/webhook/order-create", func(c *gin.Context) {
+		runs++
+		if runs == 1 {
+			c.AbortWithStatus(http.StatusInternalServerError)
+		}
+	})
+
+	for i := 0; i < 2; i++ {
+		req := httptest.NewRequest(http.MethodPost, "/webhook/order-create", bytes.NewBufferString(body))
+		req.Header.Set("X-Shopify-Shop-Domain", "example.myshopify.com")
+		req.Header.Set("X-Shopify-Hmac-Sha256", http_shopify_webhook.ComputeHMAC("secret", []byte(body)))
+		req.Header.Set("X-Shopify-Webhook-Id", "b54557e4")
+		r.ServeHTTP(httptest.NewRecorder(), req)
+	}
+
+	if runs != 2 {
+		t.Errorf("expected the retry of a failed delivery to run got %v runs", runs)
+	}
+}
+
 // Sets up the server for a few tests.
 func setupServer(key string, shop string, hmac string, body string) (*httptest.ResponseRecorder, bool) {
 	// Setup the recorder and request.
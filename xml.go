@@ -0,0 +1,14 @@
+package http_shopify_webhook
+
+import (
+	"encoding/xml"
+	"net/http"
+)
+
+// Webhook verify function wrapper which decodes the XML body for the handler,
+// for webhooks configured to be delivered as XML.
+// Bodies which fail to decode into the payload type are rejected with a 400.
+// Example: `VerifyXML("abc123", func(w http.ResponseWriter, r *http.Request, o Order) { ... })`.
+func VerifyXML[T any](key string, fn func(w http.ResponseWriter, r *http.Request, payload T), opts ...Option) http.HandlerFunc {
+	return verifyPayload(key, xml.Unmarshal, "Invalid webhook XML payload", fn, opts)
+}
@@ -0,0 +1,92 @@
+package http_shopify_webhook
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// Shape of an `orders/create` webhook delivered as XML, trimmed down.
+type testXMLOrder struct {
+	ID         int64  `xml:"id"`
+	Email      string `xml:"email"`
+	TotalPrice string `xml:"total-price"`
+	LineItems  []struct {
+		Title    string `xml:"title"`
+		Quantity int    `xml:"quantity"`
+	} `xml:"line-items>line-item"`
+}
+
+// Test the body is decoded into the payload for the handler.
+func TestVerifyXML(t *testing.T) {
+	body := `<?xml version="1.0" encoding="UTF-8"?>
+<order>
+  <id type="integer">820982911946154508</id>
+  <email>jon@doe.ca</email>
+  <total-price type="decimal">403.00</total-price>
+  <line-items type="array">
+    <line-item>
+      <title>IPod Nano - 8gb</title>
+      <quantity type="integer">1</quantity>
+    </line-item>
+  </line-items>
+</order>`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	var order testXMLOrder
+	rec := httptest.NewRecorder()
+	VerifyXML("secret", func(w http.ResponseWriter, r *http.Request, o testXMLOrder) {
+		order = o
+	}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusOK {
+		t.Errorf("expected status code %v got %v", http.StatusOK, c)
+	}
+
+	if order.ID != 820982911946154508 || order.Email != "jon@doe.ca" || order.TotalPrice != "403.00" {
+		t.Errorf("expected order fields to be populated got %+v", order)
+	}
+
+	if len(order.LineItems) != 1 || order.LineItems[0].Title != "IPod Nano - 8gb" || order.LineItems[0].Quantity != 1 {
+		t.Errorf("expected line items to be populated got %+v", order.LineItems)
+	}
+}
+
+// Test a body which is not valid XML is rejected with its own message.
+func TestVerifyXMLInvalid(t *testing.T) {
+	body := `<order><id>`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("secret", body))
+
+	ran := false
+	rec := httptest.NewRecorder()
+	VerifyXML("secret", func(w http.ResponseWriter, r *http.Request, o testXMLOrder) {
+		ran = true
+	}).ServeHTTP(rec, req)
+
+	if c := rec.Code; c != http.StatusBadRequest {
+		t.Errorf("expected status code %v got %v", http.StatusBadRequest, c)
+	}
+
+	if b := rec.Body.String(); !strings.Contains(b, "Invalid webhook XML payload") {
+		t.Errorf("expected XML payload error got %q", b)
+	}
+
+	if ran {
+		t.Errorf("expected handler to not run but did")
+	}
+}
+
+// Test the signature is verified before decoding.
+func TestVerifyXMLSignature(t *testing.T) {
+	body := `<order><id>1</id></order>`
+	req := newWebhookRequest(bytes.NewBufferString(body), "example.myshopify.com", sign("other", body))
+
+	rec := httptest.NewRecorder()
+	VerifyXML("secret", func(w http.ResponseWriter, r *http.Request, o testXMLOrder) {}).ServeHTTP(rec, req)
+
+	if b := rec.Body.String(); !strings.Contains(b, "Invalid webhook signature") {
+		t.Errorf("expected signature error got %q", b)
+	}
+}